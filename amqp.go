@@ -0,0 +1,433 @@
+package greenlight
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultAMQPPort     = "5672"
+	DefaultAMQPUsername = "guest"
+	DefaultAMQPPassword = "guest"
+	DefaultAMQPVhost    = "/"
+)
+
+// AMQP 0-9-1 frame types.
+const (
+	amqpFrameMethod    = 1
+	amqpFrameHeartbeat = 8
+	amqpFrameEnd       = 0xce
+)
+
+// AMQP 0-9-1 methods (class-id, method-id).
+var (
+	amqpConnectionStart   = [2]uint16{10, 10}
+	amqpConnectionStartOk = [2]uint16{10, 11}
+	amqpConnectionTune    = [2]uint16{10, 30}
+	amqpConnectionTuneOk  = [2]uint16{10, 31}
+	amqpConnectionOpen    = [2]uint16{10, 40}
+	amqpConnectionOpenOk  = [2]uint16{10, 41}
+	amqpConnectionClose   = [2]uint16{10, 50}
+	amqpConnectionCloseOk = [2]uint16{10, 51}
+	amqpChannelOpen       = [2]uint16{20, 10}
+	amqpChannelOpenOk     = [2]uint16{20, 11}
+	amqpChannelClose      = [2]uint16{20, 40}
+	amqpQueueDeclare      = [2]uint16{50, 10}
+	amqpQueueDeclareOk    = [2]uint16{50, 11}
+)
+
+var (
+	amqpProtocolHeader     = []byte("AMQP\x00\x00\x09\x01")
+	amqpDefaultFrameMax    = uint32(131072)
+	amqpClientProperties   = map[string]string{"product": "greenlight"}
+	errAMQPUnexpectedFrame = errors.New("unexpected frame")
+)
+
+type AMQPCheckConfig struct {
+	Host               string `yaml:"host"`
+	Port               string `yaml:"port"`
+	TLS                bool   `yaml:"tls"`
+	NoCheckCertificate bool   `yaml:"no_check_certificate"`
+	Username           string `yaml:"username"`
+	PasswordFile       string `yaml:"password_file"`
+	PasswordEnv        string `yaml:"password_env"`
+	Vhost              string `yaml:"vhost"`
+	Queue              string `yaml:"queue"`
+	MaxMessages        *int   `yaml:"max_messages"`
+	MinConsumers       *int   `yaml:"min_consumers"`
+	MaxConsumers       *int   `yaml:"max_consumers"`
+}
+
+type AMQPChecker struct {
+	Host               string
+	Port               string
+	TLS                bool
+	NoCheckCertificate bool
+	Username           string
+	PasswordFile       string
+	PasswordEnv        string
+	Vhost              string
+	Queue              string
+	MaxMessages        *int
+	MinConsumers       *int
+	MaxConsumers       *int
+	Timeout            time.Duration
+
+	name string
+}
+
+func (p *AMQPChecker) Name() string {
+	return p.name
+}
+
+func NewAMQPChecker(cfg *CheckConfig) (*AMQPChecker, error) {
+	c := cfg.AMQP
+	p := &AMQPChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		Host:               c.Host,
+		Port:               c.Port,
+		TLS:                c.TLS,
+		NoCheckCertificate: c.NoCheckCertificate,
+		Username:           c.Username,
+		PasswordFile:       c.PasswordFile,
+		PasswordEnv:        c.PasswordEnv,
+		Vhost:              c.Vhost,
+		Queue:              c.Queue,
+		MaxMessages:        c.MaxMessages,
+		MinConsumers:       c.MinConsumers,
+		MaxConsumers:       c.MaxConsumers,
+	}
+	if p.Queue == "" && (p.MaxMessages != nil || p.MinConsumers != nil || p.MaxConsumers != nil) {
+		return nil, errors.New("amqp max_messages, min_consumers and max_consumers require queue")
+	}
+	// default
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+	if p.Port == "" {
+		p.Port = DefaultAMQPPort
+	}
+	if p.Vhost == "" {
+		p.Vhost = DefaultAMQPVhost
+	}
+	return p, nil
+}
+
+func (p *AMQPChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "amqpchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	username, password := p.Username, ""
+	if p.PasswordFile != "" || p.PasswordEnv != "" {
+		var err error
+		if password, err = loadSecret(p.PasswordFile, p.PasswordEnv); err != nil {
+			return err
+		}
+	} else if username == "" {
+		username, password = DefaultAMQPUsername, DefaultAMQPPassword
+	}
+
+	addr := net.JoinHostPort(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, p.TLS, p.NoCheckCertificate, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("amqp connect failed: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+	logger.Debug("connected " + addr)
+
+	ac := &amqpConn{w: conn, r: bufio.NewReader(conn)}
+	if _, err := conn.Write(amqpProtocolHeader); err != nil {
+		return fmt.Errorf("amqp send protocol header failed: %w", err)
+	}
+
+	// Start / StartOk
+	args, err := ac.expect(0, amqpConnectionStart)
+	if err != nil {
+		return fmt.Errorf("amqp Connection.Start: %w", err)
+	}
+	mechanisms, err := parseAMQPStart(args)
+	if err != nil {
+		return fmt.Errorf("amqp Connection.Start: %w", err)
+	}
+	logger.Debug("server mechanisms " + mechanisms)
+	if !containsField(mechanisms, "PLAIN") {
+		return fmt.Errorf("amqp PLAIN mechanism is not supported by the server: %s", mechanisms)
+	}
+	var b amqpBuffer
+	b.table(amqpClientProperties)
+	b.shortstr("PLAIN")
+	b.longstr("\x00" + username + "\x00" + password)
+	b.shortstr("en_US")
+	if err := ac.writeMethod(0, amqpConnectionStartOk, b); err != nil {
+		return fmt.Errorf("amqp Connection.StartOk failed: %w", err)
+	}
+
+	// Tune / TuneOk. An authentication failure is reported by Connection.Close or closing the socket.
+	args, err = ac.expect(0, amqpConnectionTune)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("amqp connection closed by the server after StartOk (authentication failed?)")
+		}
+		return fmt.Errorf("amqp Connection.Tune: %w", err)
+	}
+	if len(args) < 8 {
+		return fmt.Errorf("amqp Connection.Tune: %w", errAMQPUnexpectedFrame)
+	}
+	channelMax := binary.BigEndian.Uint16(args[0:2])
+	frameMax := binary.BigEndian.Uint32(args[2:6])
+	if frameMax == 0 || frameMax > amqpDefaultFrameMax {
+		frameMax = amqpDefaultFrameMax
+	}
+	b = amqpBuffer{}
+	b.uint16(channelMax)
+	b.uint32(frameMax)
+	b.uint16(0) // no heartbeat
+	if err := ac.writeMethod(0, amqpConnectionTuneOk, b); err != nil {
+		return fmt.Errorf("amqp Connection.TuneOk failed: %w", err)
+	}
+
+	// Open vhost
+	b = amqpBuffer{}
+	b.shortstr(p.Vhost)
+	b.shortstr("")   // reserved
+	b = append(b, 0) // reserved
+	if err := ac.writeMethod(0, amqpConnectionOpen, b); err != nil {
+		return fmt.Errorf("amqp Connection.Open failed: %w", err)
+	}
+	if _, err := ac.expect(0, amqpConnectionOpenOk); err != nil {
+		return fmt.Errorf("amqp Connection.Open vhost %s: %w", p.Vhost, err)
+	}
+	logger.Debug("opened vhost " + p.Vhost)
+
+	if p.Queue != "" {
+		if err := p.declareQueue(ac, logger); err != nil {
+			return err
+		}
+	}
+
+	// Close
+	b = amqpBuffer{}
+	b.uint16(200)
+	b.shortstr("")
+	b.uint16(0)
+	b.uint16(0)
+	if err := ac.writeMethod(0, amqpConnectionClose, b); err != nil {
+		return fmt.Errorf("amqp Connection.Close failed: %w", err)
+	}
+	if _, err := ac.expect(0, amqpConnectionCloseOk); err != nil {
+		return fmt.Errorf("amqp Connection.Close: %w", err)
+	}
+	return nil
+}
+
+// declareQueue declares the queue passively on channel 1, and checks the counts.
+func (p *AMQPChecker) declareQueue(ac *amqpConn, logger *slog.Logger) error {
+	var b amqpBuffer
+	b.shortstr("") // reserved
+	if err := ac.writeMethod(1, amqpChannelOpen, b); err != nil {
+		return fmt.Errorf("amqp Channel.Open failed: %w", err)
+	}
+	if _, err := ac.expect(1, amqpChannelOpenOk); err != nil {
+		return fmt.Errorf("amqp Channel.Open: %w", err)
+	}
+
+	b = amqpBuffer{}
+	b.uint16(0) // reserved
+	b.shortstr(p.Queue)
+	b = append(b, 0x01) // passive
+	b.table(nil)
+	if err := ac.writeMethod(1, amqpQueueDeclare, b); err != nil {
+		return fmt.Errorf("amqp Queue.Declare failed: %w", err)
+	}
+	args, err := ac.expect(1, amqpQueueDeclareOk)
+	if err != nil {
+		return fmt.Errorf("amqp Queue.Declare %s: %w", p.Queue, err)
+	}
+	r := amqpReader(args)
+	if _, err := r.shortstr(); err != nil || len(r) < 8 {
+		return fmt.Errorf("amqp Queue.DeclareOk: %w", errAMQPUnexpectedFrame)
+	}
+	messages := int(binary.BigEndian.Uint32(r[0:4]))
+	consumers := int(binary.BigEndian.Uint32(r[4:8]))
+	logger.Debug(fmt.Sprintf("queue %s messages %d consumers %d", p.Queue, messages, consumers))
+
+	if p.MaxMessages != nil && messages > *p.MaxMessages {
+		return fmt.Errorf("amqp queue %s messages %d exceeds max_messages %d", p.Queue, messages, *p.MaxMessages)
+	}
+	if p.MinConsumers != nil && consumers < *p.MinConsumers {
+		return fmt.Errorf("amqp queue %s consumers %d is less than min_consumers %d", p.Queue, consumers, *p.MinConsumers)
+	}
+	if p.MaxConsumers != nil && consumers > *p.MaxConsumers {
+		return fmt.Errorf("amqp queue %s consumers %d exceeds max_consumers %d", p.Queue, consumers, *p.MaxConsumers)
+	}
+	return nil
+}
+
+func parseAMQPStart(args []byte) (string, error) {
+	r := amqpReader(args)
+	if len(r) < 2 {
+		return "", errAMQPUnexpectedFrame
+	}
+	if r[0] != 0 || r[1] != 9 {
+		return "", fmt.Errorf("unsupported protocol version %d-%d", r[0], r[1])
+	}
+	r = r[2:]
+	if _, err := r.longstr(); err != nil { // server-properties table is skipped as a long string
+		return "", err
+	}
+	return r.longstr()
+}
+
+func containsField(s, v string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+type amqpConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+func (c *amqpConn) writeMethod(channel uint16, method [2]uint16, args []byte) error {
+	payload := make([]byte, 0, 4+len(args))
+	payload = binary.BigEndian.AppendUint16(payload, method[0])
+	payload = binary.BigEndian.AppendUint16(payload, method[1])
+	payload = append(payload, args...)
+
+	frame := []byte{amqpFrameMethod}
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = append(frame, amqpFrameEnd)
+	_, err := c.w.Write(frame)
+	return err
+}
+
+// readMethod reads a method frame. Heartbeat frames are skipped.
+func (c *amqpConn) readMethod() (channel uint16, method [2]uint16, args []byte, err error) {
+	for {
+		var h [7]byte
+		if _, err = io.ReadFull(c.r, h[:]); err != nil {
+			return
+		}
+		typ := h[0]
+		channel = binary.BigEndian.Uint16(h[1:3])
+		size := binary.BigEndian.Uint32(h[3:7])
+		if size > amqpDefaultFrameMax {
+			err = fmt.Errorf("frame size %d is too large", size)
+			return
+		}
+		payload := make([]byte, size+1)
+		if _, err = io.ReadFull(c.r, payload); err != nil {
+			return
+		}
+		if payload[size] != amqpFrameEnd {
+			err = errors.New("invalid frame end")
+			return
+		}
+		if typ == amqpFrameHeartbeat {
+			continue
+		}
+		if typ != amqpFrameMethod || size < 4 {
+			err = fmt.Errorf("%w: type %d", errAMQPUnexpectedFrame, typ)
+			return
+		}
+		method = [2]uint16{binary.BigEndian.Uint16(payload[0:2]), binary.BigEndian.Uint16(payload[2:4])}
+		args = payload[4:size]
+		return
+	}
+}
+
+// expect reads a method frame and returns its arguments.
+// Connection.Close and Channel.Close from the server are returned as errors with the reply text.
+func (c *amqpConn) expect(channel uint16, method [2]uint16) ([]byte, error) {
+	ch, m, args, err := c.readMethod()
+	if err != nil {
+		return nil, err
+	}
+	if m == amqpConnectionClose || m == amqpChannelClose {
+		r := amqpReader(args)
+		if len(r) < 2 {
+			return nil, errAMQPUnexpectedFrame
+		}
+		code := binary.BigEndian.Uint16(r[0:2])
+		r = r[2:]
+		text, _ := r.shortstr()
+		return nil, fmt.Errorf("closed by the server: %d %s", code, text)
+	}
+	if ch != channel || m != method {
+		return nil, fmt.Errorf("%w: channel %d method %d.%d", errAMQPUnexpectedFrame, ch, m[0], m[1])
+	}
+	return args, nil
+}
+
+type amqpBuffer []byte
+
+func (b *amqpBuffer) uint16(v uint16) {
+	*b = binary.BigEndian.AppendUint16(*b, v)
+}
+
+func (b *amqpBuffer) uint32(v uint32) {
+	*b = binary.BigEndian.AppendUint32(*b, v)
+}
+
+func (b *amqpBuffer) shortstr(s string) {
+	*b = append(*b, byte(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *amqpBuffer) longstr(s string) {
+	b.uint32(uint32(len(s)))
+	*b = append(*b, s...)
+}
+
+// table writes a field table with long string values.
+func (b *amqpBuffer) table(m map[string]string) {
+	var t amqpBuffer
+	for k, v := range m {
+		t.shortstr(k)
+		t = append(t, 'S')
+		t.longstr(v)
+	}
+	b.longstr(string(t))
+}
+
+type amqpReader []byte
+
+func (r *amqpReader) shortstr() (string, error) {
+	if len(*r) < 1 || len(*r) < 1+int((*r)[0]) {
+		return "", errAMQPUnexpectedFrame
+	}
+	n := int((*r)[0])
+	s := string((*r)[1 : 1+n])
+	*r = (*r)[1+n:]
+	return s, nil
+}
+
+func (r *amqpReader) longstr() (string, error) {
+	if len(*r) < 4 {
+		return "", errAMQPUnexpectedFrame
+	}
+	n := binary.BigEndian.Uint32((*r)[0:4])
+	if uint64(len(*r)) < 4+uint64(n) {
+		return "", errAMQPUnexpectedFrame
+	}
+	s := string((*r)[4 : 4+n])
+	*r = (*r)[4+n:]
+	return s, nil
+}
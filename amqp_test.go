@@ -0,0 +1,150 @@
+package greenlight_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func writeAMQPMethod(w io.Writer, channel, class, method uint16, args []byte) {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	payload = append(payload, args...)
+	frame := []byte{1}
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(append(frame, payload...), 0xce)
+	w.Write(frame)
+}
+
+func readAMQPMethod(r io.Reader) (class, method uint16, args []byte, err error) {
+	var h [7]byte
+	if _, err = io.ReadFull(r, h[:]); err != nil {
+		return
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(h[3:7])+1)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	return binary.BigEndian.Uint16(payload[0:2]), binary.BigEndian.Uint16(payload[2:4]), payload[4 : len(payload)-1], nil
+}
+
+func amqpShortstr(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func amqpLongstr(s string) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+// serveAMQP is a minimal RabbitMQ. user "app" password "secret", vhost "/app", queue "jobs" has 5 messages and 2 consumers.
+func serveAMQP(conn net.Conn) {
+	defer conn.Close()
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil || string(header) != "AMQP\x00\x00\x09\x01" {
+		return
+	}
+	start := append([]byte{0, 9}, amqpLongstr("")...) // empty server-properties
+	start = append(start, amqpLongstr("AMQPLAIN PLAIN")...)
+	start = append(start, amqpLongstr("en_US")...)
+	writeAMQPMethod(conn, 0, 10, 10, start)
+	for {
+		class, method, args, err := readAMQPMethod(conn)
+		if err != nil {
+			return
+		}
+		switch [2]uint16{class, method} {
+		case [2]uint16{10, 11}: // StartOk
+			if !bytes.Contains(args, []byte("\x00app\x00secret")) {
+				return // RabbitMQ closes the socket on authentication failure
+			}
+			writeAMQPMethod(conn, 0, 10, 30, []byte{0, 0x7f, 0, 2, 0, 0, 0, 60})
+		case [2]uint16{10, 31}: // TuneOk
+		case [2]uint16{10, 40}: // Open
+			if !bytes.HasPrefix(args, amqpShortstr("/app")) {
+				writeAMQPMethod(conn, 0, 10, 50, append(binary.BigEndian.AppendUint16(nil, 530), amqpShortstr("NOT_ALLOWED")...))
+				continue
+			}
+			writeAMQPMethod(conn, 0, 10, 41, amqpShortstr(""))
+		case [2]uint16{20, 10}: // Channel.Open
+			writeAMQPMethod(conn, 1, 20, 11, amqpLongstr(""))
+		case [2]uint16{50, 10}: // Queue.Declare
+			if !bytes.HasPrefix(args[2:], amqpShortstr("jobs")) {
+				writeAMQPMethod(conn, 1, 20, 40, append(binary.BigEndian.AppendUint16(nil, 404), amqpShortstr("NOT_FOUND - no queue")...))
+				continue
+			}
+			ok := amqpShortstr("jobs")
+			ok = binary.BigEndian.AppendUint32(ok, 5)
+			ok = binary.BigEndian.AppendUint32(ok, 2)
+			writeAMQPMethod(conn, 1, 50, 11, ok)
+		case [2]uint16{10, 50}: // Close
+			writeAMQPMethod(conn, 0, 10, 51, nil)
+			return
+		}
+	}
+}
+
+func TestAMQPChecker(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveAMQP(conn)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	t.Setenv("AMQP_PASSWORD", "secret")
+	t.Setenv("AMQP_WRONG_PASSWORD", "wrong")
+	n := func(v int) *int { return &v }
+
+	tests := []struct {
+		name      string
+		cfg       greenlight.AMQPCheckConfig
+		expectErr bool
+	}{
+		{"open", greenlight.AMQPCheckConfig{}, false},
+		{"auth failed", greenlight.AMQPCheckConfig{PasswordEnv: "AMQP_WRONG_PASSWORD"}, true},
+		{"vhost not allowed", greenlight.AMQPCheckConfig{Vhost: "/other"}, true},
+		{"queue", greenlight.AMQPCheckConfig{Queue: "jobs", MaxMessages: n(10), MinConsumers: n(1)}, false},
+		{"queue not found", greenlight.AMQPCheckConfig{Queue: "missing"}, true},
+		{"max_messages exceeded", greenlight.AMQPCheckConfig{Queue: "jobs", MaxMessages: n(4)}, true},
+		{"min_consumers not met", greenlight.AMQPCheckConfig{Queue: "jobs", MinConsumers: n(3)}, true},
+		{"max_consumers exceeded", greenlight.AMQPCheckConfig{Queue: "jobs", MaxConsumers: n(1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Host, tt.cfg.Port, tt.cfg.Username = host, port, "app"
+			if tt.cfg.PasswordEnv == "" {
+				tt.cfg.PasswordEnv = "AMQP_PASSWORD"
+			}
+			if tt.cfg.Vhost == "" {
+				tt.cfg.Vhost = "/app"
+			}
+			checker, err := greenlight.NewAMQPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				AMQP:    &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package greenlight
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultCgroupRoot              = "/sys/fs/cgroup"
+	DefaultCgroupCPUSampleDuration = 1 * time.Second
+)
+
+type CgroupCheckConfig struct {
+	Path              string        `yaml:"path"`
+	Pressure          string        `yaml:"pressure"`
+	PressureWindow    int           `yaml:"pressure_window"`
+	MaxMemoryPressure float64       `yaml:"max_memory_pressure"`
+	MaxCPUPressure    float64       `yaml:"max_cpu_pressure"`
+	MaxIOPressure     float64       `yaml:"max_io_pressure"`
+	MaxMemoryUsage    SizeThreshold `yaml:"max_memory_usage"`
+	MaxCPUThrottled   float64       `yaml:"max_cpu_throttled"`
+}
+
+type CgroupChecker struct {
+	Path              string
+	Pressure          string // some or full
+	PressureWindow    int    // 10, 60, or 300 seconds
+	MaxMemoryPressure float64
+	MaxCPUPressure    float64
+	MaxIOPressure     float64
+	MaxMemoryUsage    *sizeLimit
+	MaxCPUThrottled   float64
+	Timeout           time.Duration
+
+	name string
+}
+
+func (p *CgroupChecker) Name() string {
+	return p.name
+}
+
+func NewCgroupChecker(cfg *CheckConfig) (*CgroupChecker, error) {
+	c := cfg.Cgroup
+	p := &CgroupChecker{
+		name:              cfg.Name,
+		Timeout:           cfg.Timeout,
+		Path:              c.Path,
+		Pressure:          c.Pressure,
+		PressureWindow:    c.PressureWindow,
+		MaxMemoryPressure: c.MaxMemoryPressure,
+		MaxCPUPressure:    c.MaxCPUPressure,
+		MaxIOPressure:     c.MaxIOPressure,
+		MaxCPUThrottled:   c.MaxCPUThrottled,
+	}
+	var err error
+	if p.MaxMemoryUsage, err = parseSizeThreshold(c.MaxMemoryUsage, true); err != nil {
+		return nil, fmt.Errorf("invalid max_memory_usage: %w", err)
+	}
+	if p.MaxMemoryPressure == 0 && p.MaxCPUPressure == 0 && p.MaxIOPressure == 0 && p.MaxMemoryUsage == nil && p.MaxCPUThrottled == 0 {
+		return nil, errors.New("cgroup requires one of max_memory_pressure, max_cpu_pressure, max_io_pressure, max_memory_usage, or max_cpu_throttled")
+	}
+	// default
+	switch p.Pressure {
+	case "":
+		p.Pressure = "some"
+	case "some", "full":
+	default:
+		return nil, fmt.Errorf("invalid pressure %s: must be some or full", p.Pressure)
+	}
+	switch p.PressureWindow {
+	case 0:
+		p.PressureWindow = 10
+	case 10, 60, 300:
+	default:
+		return nil, fmt.Errorf("invalid pressure_window %d: must be 10, 60, or 300", p.PressureWindow)
+	}
+	if p.Path == "" {
+		p.Path = ownCgroupPath(DefaultProcRoot, DefaultCgroupRoot)
+	}
+	return p, nil
+}
+
+func (p *CgroupChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "cgroupchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	for _, r := range []struct {
+		file string
+		max  float64
+	}{
+		{"memory.pressure", p.MaxMemoryPressure},
+		{"cpu.pressure", p.MaxCPUPressure},
+		{"io.pressure", p.MaxIOPressure},
+	} {
+		if r.max <= 0 {
+			continue
+		}
+		v, err := readPressure(filepath.Join(p.Path, r.file), p.Pressure, p.PressureWindow)
+		if err != nil {
+			return fmt.Errorf("read %s failed: %w", r.file, err)
+		}
+		logger.Debug(fmt.Sprintf("%s %s avg%d=%.2f", r.file, p.Pressure, p.PressureWindow, v))
+		if v > r.max {
+			return fmt.Errorf("%s %s avg%d %.2f%% exceeds %.2f%%", r.file, p.Pressure, p.PressureWindow, v, r.max)
+		}
+	}
+
+	if p.MaxMemoryUsage != nil {
+		current, err := readCgroupValue(filepath.Join(p.Path, "memory.current"))
+		if err != nil {
+			return fmt.Errorf("read memory.current failed: %w", err)
+		}
+		limit, err := readCgroupValue(filepath.Join(p.Path, "memory.max"))
+		if err != nil {
+			return fmt.Errorf("read memory.max failed: %w", err)
+		}
+		logger.Debug(fmt.Sprintf("memory %d/%d bytes (%.1f%%)", current, limit, percentOf(current, limit)))
+		switch {
+		case p.MaxMemoryUsage.Percent && limit == 0:
+			logger.Debug("memory.max is not limited, max_memory_usage in percent is ignored")
+		case p.MaxMemoryUsage.above(current, limit):
+			return fmt.Errorf("memory usage %d bytes (%.1f%% of memory.max %d) exceeds max_memory_usage %s", current, percentOf(current, limit), limit, p.MaxMemoryUsage)
+		}
+	}
+
+	if p.MaxCPUThrottled > 0 {
+		throttled, err := p.sampleCPUThrottled(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Debug(fmt.Sprintf("cpu throttled %.1f%% of periods", throttled))
+		if throttled > p.MaxCPUThrottled {
+			return fmt.Errorf("cpu throttled %.1f%% of periods exceeds max_cpu_throttled %.1f%%", throttled, p.MaxCPUThrottled)
+		}
+	}
+	return nil
+}
+
+// sampleCPUThrottled returns the percentage of throttled periods in cpu.stat in a sample duration.
+func (p *CgroupChecker) sampleCPUThrottled(ctx context.Context) (float64, error) {
+	file := filepath.Join(p.Path, "cpu.stat")
+	before, err := readFlatKeyed(file)
+	if err != nil {
+		return 0, fmt.Errorf("read cpu.stat failed: %w", err)
+	}
+	select {
+	case <-time.After(min(DefaultCgroupCPUSampleDuration, p.Timeout/2)):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	after, err := readFlatKeyed(file)
+	if err != nil {
+		return 0, fmt.Errorf("read cpu.stat failed: %w", err)
+	}
+	return cpuThrottledPercent(before, after), nil
+}
+
+// cpuThrottledPercent returns the percentage of throttled periods between two samples of cpu.stat.
+func cpuThrottledPercent(before, after map[string]uint64) float64 {
+	// nr_periods is not counted without cpu.max limit.
+	periods := after["nr_periods"] - min(before["nr_periods"], after["nr_periods"])
+	throttled := after["nr_throttled"] - min(before["nr_throttled"], after["nr_throttled"])
+	return percentOf(throttled, periods)
+}
+
+// ownCgroupPath returns the cgroup v2 directory of this process.
+// In a container with a cgroup namespace, it is the cgroup root itself.
+func ownCgroupPath(procRoot, cgroupRoot string) string {
+	b, err := os.ReadFile(filepath.Join(procRoot, "self", "cgroup"))
+	if err != nil {
+		return cgroupRoot
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// cgroup v2 has a single line "0::/path/to/cgroup".
+		if rel, ok := strings.CutPrefix(line, "0::"); ok {
+			dir := filepath.Join(cgroupRoot, rel)
+			if _, err := os.Stat(dir); err == nil {
+				return dir
+			}
+		}
+	}
+	return cgroupRoot
+}
+
+// readPressure reads a PSI file and returns the avg<window> value of the line.
+// e.g. "some avg10=1.23 avg60=0.50 avg300=0.10 total=123456"
+func readPressure(file, line string, window int) (float64, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	key := fmt.Sprintf("avg%d=", window)
+	for _, l := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(l)
+		if len(fields) == 0 || fields[0] != line {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(f, key); ok {
+				return strconv.ParseFloat(v, 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s %s is not found", line, key)
+}
+
+// readCgroupValue reads a single value file. "max" means unlimited and returns 0.
+func readCgroupValue(file string) (uint64, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readFlatKeyed reads a flat keyed file like cpu.stat. e.g. "nr_periods 123"
+func readFlatKeyed(file string) (map[string]uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = n
+	}
+	return values, s.Err()
+}
@@ -0,0 +1,121 @@
+package greenlight_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestCgroupChecker(t *testing.T) {
+	// testdata/cgroup: memory some avg10=12.50, cpu some avg10=30.00 avg300=20.00,
+	// io some avg10=0.50, memory 768MiB of 1GiB.
+	tests := []struct {
+		name      string
+		cfg       greenlight.CgroupCheckConfig
+		expectErr bool
+	}{
+		{"memory pressure", greenlight.CgroupCheckConfig{MaxMemoryPressure: 20}, false},
+		{"memory pressure exceeded", greenlight.CgroupCheckConfig{MaxMemoryPressure: 10}, true},
+		{"memory pressure full", greenlight.CgroupCheckConfig{MaxMemoryPressure: 10, Pressure: "full"}, false},
+		{"cpu pressure exceeded", greenlight.CgroupCheckConfig{MaxCPUPressure: 25}, true},
+		{"cpu pressure avg300", greenlight.CgroupCheckConfig{MaxCPUPressure: 25, PressureWindow: 300}, false},
+		{"io pressure", greenlight.CgroupCheckConfig{MaxIOPressure: 1}, false},
+		{"memory usage", greenlight.CgroupCheckConfig{MaxMemoryUsage: "80%"}, false},
+		{"memory usage exceeded", greenlight.CgroupCheckConfig{MaxMemoryUsage: "70%"}, true},
+		{"memory usage bytes exceeded", greenlight.CgroupCheckConfig{MaxMemoryUsage: "512MiB"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = "testdata/cgroup"
+			checker, err := greenlight.NewCgroupChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: 100 * time.Millisecond,
+				Cgroup:  &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCgroupCheckerUnlimitedMemory(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"memory.current": "805306368\n",
+		"memory.max":     "max\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		threshold greenlight.SizeThreshold
+		expectErr bool
+	}{
+		{"1%", false}, // percentage is ignored without memory.max
+		{"1GiB", false},
+		{"512MiB", true},
+	} {
+		checker, err := greenlight.NewCgroupChecker(&greenlight.CheckConfig{
+			Name:    "unlimited",
+			Timeout: time.Second,
+			Cgroup:  &greenlight.CgroupCheckConfig{Path: dir, MaxMemoryUsage: tt.threshold},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checker.Run(context.Background()); (err != nil) != tt.expectErr {
+			t.Errorf("%s: unexpected error: %v", tt.threshold, err)
+		}
+	}
+}
+
+func TestCgroupCPUThrottled(t *testing.T) {
+	dir := t.TempDir()
+	// stat writes the counters of cpu.stat, and reads them back as the checker does.
+	stat := func(name, content string) map[string]uint64 {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m, err := greenlight.ReadFlatKeyed(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	fixture, err := greenlight.ReadFlatKeyed("testdata/cgroup/cpu.stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		before map[string]uint64
+		after  string
+		expect float64
+	}{
+		{"throttled", fixture, "usage_usec 123556789\nnr_periods 10100\nnr_throttled 2530\n", 30},
+		{"not throttled", fixture, "usage_usec 123556789\nnr_periods 10100\nnr_throttled 2500\n", 0},
+		{"no periods elapsed", fixture, "usage_usec 123556789\nnr_periods 10000\nnr_throttled 2500\n", 0},
+		{"without cpu.max", stat("unlimited", "usage_usec 100\n"), "usage_usec 200\n", 0},
+		{"counters reset", fixture, "usage_usec 100\nnr_periods 10\nnr_throttled 5\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := stat("cpu.stat", tt.after)
+			if got := greenlight.CPUThrottledPercent(tt.before, after); got != tt.expect {
+				t.Errorf("expected %v%%, got %v%%", tt.expect, got)
+			}
+		})
+	}
+}
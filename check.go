@@ -2,6 +2,7 @@ package greenlight
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
@@ -17,7 +18,74 @@ func NewChecker(cfg *CheckConfig) (Checker, error) {
 		return NewTCPChecker(cfg)
 	} else if cfg.HTTP != nil {
 		return NewHTTPChecker(cfg)
+	} else if cfg.DNS != nil {
+		return NewDNSChecker(cfg)
+	} else if cfg.Redis != nil {
+		return NewRedisChecker(cfg)
+	} else if cfg.Postgres != nil {
+		return NewPostgresChecker(cfg)
+	} else if cfg.MySQL != nil {
+		return NewMySQLChecker(cfg)
+	} else if cfg.SQL != nil {
+		return NewSQLChecker(cfg)
+	} else if cfg.UDP != nil {
+		return NewUDPChecker(cfg)
+	} else if cfg.File != nil {
+		return NewFileChecker(cfg)
+	} else if cfg.Process != nil {
+		return NewProcessChecker(cfg)
+	} else if cfg.Disk != nil {
+		return NewDiskChecker(cfg)
+	} else if cfg.System != nil {
+		return NewSystemChecker(cfg)
+	} else if cfg.Cgroup != nil {
+		return NewCgroupChecker(cfg)
+	} else if cfg.TLS != nil {
+		return NewTLSChecker(cfg)
+	} else if cfg.WebSocket != nil {
+		return NewWebSocketChecker(cfg)
+	} else if cfg.SMTP != nil {
+		return NewSMTPChecker(cfg)
+	} else if cfg.AMQP != nil {
+		return NewAMQPChecker(cfg)
+	} else if cfg.Metrics != nil {
+		return NewMetricsChecker(cfg)
 	} else {
-		return nil, fmt.Errorf("invalid check config. command, tcp, or http section is required: %v", cfg)
+		return nil, fmt.Errorf("invalid check config. command, tcp, http, dns, redis, postgres, mysql, sql, udp, file, process, disk, system, cgroup, tls, websocket, smtp, amqp, or metrics section is required: %v", cfg)
 	}
 }
+
+// WarningError is a check result that is degraded but not failed.
+// It signals yellow in readiness checks, and does not block startup checks.
+type WarningError struct {
+	Err error
+}
+
+func (e *WarningError) Error() string {
+	return "warning: " + e.Err.Error()
+}
+
+func (e *WarningError) Unwrap() error {
+	return e.Err
+}
+
+func newWarning(format string, args ...any) error {
+	return &WarningError{Err: fmt.Errorf(format, args...)}
+}
+
+// isWarning reports whether err is not nil and all the errors in err are warnings.
+func isWarning(err error) bool {
+	if err == nil {
+		return false
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range j.Unwrap() {
+			if !isWarning(e) {
+				return false
+			}
+		}
+		return true
+	}
+	var w *WarningError
+	return errors.As(err, &w)
+}
@@ -0,0 +1,40 @@
+package greenlight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var compareOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// newCompareFunc parses a comparison expression like "> 0", "<= 1.5", or "10" (equal),
+// and returns a function that compares the given number with it.
+func newCompareFunc(expr string) (func(v float64) bool, error) {
+	s := strings.TrimSpace(expr)
+	op := "=="
+	for _, o := range compareOperators {
+		if strings.HasPrefix(s, o) {
+			op, s = o, strings.TrimSpace(s[len(o):])
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comparison %q: %w", expr, err)
+	}
+	switch op {
+	case ">=":
+		return func(v float64) bool { return v >= n }, nil
+	case "<=":
+		return func(v float64) bool { return v <= n }, nil
+	case ">":
+		return func(v float64) bool { return v > n }, nil
+	case "<":
+		return func(v float64) bool { return v < n }, nil
+	case "!=":
+		return func(v float64) bool { return v != n }, nil
+	default:
+		return func(v float64) bool { return v == n }, nil
+	}
+}
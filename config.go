@@ -29,22 +29,42 @@ type Config struct {
 }
 
 type ResponderConfig struct {
-	Addr string `yaml:"addr"`
+	Addr      string          `yaml:"addr"`
+	Admin     *AdminConfig    `yaml:"admin"`
+	OnDemand  *OnDemandConfig `yaml:"on_demand"`
+	SlowStart time.Duration   `yaml:"slow_start"`
 }
 
 type PhaseConfig struct {
 	Checks      []*CheckConfig `yaml:"checks"`
 	Interval    time.Duration  `yaml:"interval"`
 	GracePeriod time.Duration  `yaml:"grace_period"`
+	StaleFactor float64        `yaml:"stale_factor"` // readiness only, ignored for startup
 }
 
 type CheckConfig struct {
 	Name    string        `yaml:"name"`
 	Timeout time.Duration `yaml:"timeout"`
 
-	Command *CommandCheckConfig `yaml:"command"`
-	TCP     *TCPCheckConfig     `yaml:"tcp"`
-	HTTP    *HTTPCheckConfig    `yaml:"http"`
+	Command   *CommandCheckConfig   `yaml:"command"`
+	TCP       *TCPCheckConfig       `yaml:"tcp"`
+	HTTP      *HTTPCheckConfig      `yaml:"http"`
+	DNS       *DNSCheckConfig       `yaml:"dns"`
+	Redis     *RedisCheckConfig     `yaml:"redis"`
+	Postgres  *PostgresCheckConfig  `yaml:"postgres"`
+	MySQL     *MySQLCheckConfig     `yaml:"mysql"`
+	SQL       *SQLCheckConfig       `yaml:"sql"`
+	UDP       *UDPCheckConfig       `yaml:"udp"`
+	File      *FileCheckConfig      `yaml:"file"`
+	Process   *ProcessCheckConfig   `yaml:"process"`
+	Disk      *DiskCheckConfig      `yaml:"disk"`
+	System    *SystemCheckConfig    `yaml:"system"`
+	Cgroup    *CgroupCheckConfig    `yaml:"cgroup"`
+	TLS       *TLSCheckConfig       `yaml:"tls"`
+	WebSocket *WebSocketCheckConfig `yaml:"websocket"`
+	SMTP      *SMTPCheckConfig      `yaml:"smtp"`
+	AMQP      *AMQPCheckConfig      `yaml:"amqp"`
+	Metrics   *MetricsCheckConfig   `yaml:"metrics"`
 }
 
 func LoadConfig(ctx context.Context, src string) (*Config, error) {
@@ -126,3 +146,19 @@ func loadS3(ctx context.Context, u *url.URL) ([]byte, error) {
 	defer out.Body.Close()
 	return io.ReadAll(out.Body)
 }
+
+// loadSecret reads a secret from the file, or from the environment variable.
+// The file takes precedence. Leading and trailing spaces are trimmed.
+func loadSecret(file, env string) (string, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %w", file, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	if env != "" {
+		return strings.TrimSpace(os.Getenv(env)), nil
+	}
+	return "", nil
+}
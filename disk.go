@@ -0,0 +1,143 @@
+package greenlight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type DiskCheckConfig struct {
+	Path           string        `yaml:"path"`
+	MinFree        DiskThreshold `yaml:"min_free"`
+	MinFreeInodes  DiskThreshold `yaml:"min_free_inodes"`
+	WarnFree       DiskThreshold `yaml:"warn_free"`
+	WarnFreeInodes DiskThreshold `yaml:"warn_free_inodes"`
+}
+
+// DiskThreshold is an absolute value like "1GiB" (or "10000" for inodes), or a percentage like "10%".
+type DiskThreshold string
+
+func (v *DiskThreshold) UnmarshalYAML(b []byte) error {
+	*v = DiskThreshold(strings.Trim(strings.TrimSpace(string(b)), `"'`))
+	return nil
+}
+
+// diskLimit is a parsed DiskThreshold.
+type diskLimit struct {
+	Value   float64
+	Percent bool
+}
+
+func (l *diskLimit) String() string {
+	return formatThreshold(l.Value, l.Percent)
+}
+
+// exceeded reports whether the free amount is below the limit.
+func (l *diskLimit) exceeded(free, total uint64) bool {
+	if l.Percent {
+		return diskPercent(free, total) < l.Value
+	}
+	return float64(free) < l.Value
+}
+
+func parseDiskThreshold(v DiskThreshold, bytes bool) (*diskLimit, error) {
+	s := strings.TrimSpace(string(v))
+	if s == "" {
+		return nil, nil
+	}
+	f, percent, err := parseThreshold(s, bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &diskLimit{Value: f, Percent: percent}, nil
+}
+
+type DiskChecker struct {
+	Path           string
+	MinFree        *diskLimit
+	MinFreeInodes  *diskLimit
+	WarnFree       *diskLimit
+	WarnFreeInodes *diskLimit
+
+	name string
+}
+
+func (p *DiskChecker) Name() string {
+	return p.name
+}
+
+func NewDiskChecker(cfg *CheckConfig) (*DiskChecker, error) {
+	c := cfg.Disk
+	p := &DiskChecker{
+		name: cfg.Name,
+		Path: c.Path,
+	}
+	if p.Path == "" {
+		return nil, errors.New("disk path is required")
+	}
+	var err error
+	if p.MinFree, err = parseDiskThreshold(c.MinFree, true); err != nil {
+		return nil, fmt.Errorf("invalid min_free: %w", err)
+	}
+	if p.MinFreeInodes, err = parseDiskThreshold(c.MinFreeInodes, false); err != nil {
+		return nil, fmt.Errorf("invalid min_free_inodes: %w", err)
+	}
+	if p.WarnFree, err = parseDiskThreshold(c.WarnFree, true); err != nil {
+		return nil, fmt.Errorf("invalid warn_free: %w", err)
+	}
+	if p.WarnFreeInodes, err = parseDiskThreshold(c.WarnFreeInodes, false); err != nil {
+		return nil, fmt.Errorf("invalid warn_free_inodes: %w", err)
+	}
+	if p.MinFree == nil && p.MinFreeInodes == nil && p.WarnFree == nil && p.WarnFreeInodes == nil {
+		return nil, errors.New("disk requires one of min_free, min_free_inodes, warn_free, or warn_free_inodes")
+	}
+	return p, nil
+}
+
+// diskStat is a usage of the filesystem.
+// Free is the amount available to unprivileged users.
+type diskStat struct {
+	TotalBytes uint64
+	FreeBytes  uint64
+	TotalFiles uint64
+	FreeFiles  uint64
+}
+
+func (p *DiskChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "diskchecker")
+
+	st, err := diskUsage(p.Path)
+	if err != nil {
+		return fmt.Errorf("disk statfs failed: %w", err)
+	}
+	logger.Debug(fmt.Sprintf("%s free %d/%d bytes (%.1f%%), free %d/%d inodes (%.1f%%)",
+		p.Path,
+		st.FreeBytes, st.TotalBytes, diskPercent(st.FreeBytes, st.TotalBytes),
+		st.FreeFiles, st.TotalFiles, diskPercent(st.FreeFiles, st.TotalFiles),
+	))
+
+	if p.MinFree != nil && p.MinFree.exceeded(st.FreeBytes, st.TotalBytes) {
+		return fmt.Errorf("disk %s free space %s is below min_free %s", p.Path, formatDiskFree(st.FreeBytes, st.TotalBytes), p.MinFree)
+	}
+	// some filesystems (e.g. btrfs) do not report the number of inodes.
+	inodes := st.TotalFiles > 0
+	if p.MinFreeInodes != nil && inodes && p.MinFreeInodes.exceeded(st.FreeFiles, st.TotalFiles) {
+		return fmt.Errorf("disk %s free inodes %s is below min_free_inodes %s", p.Path, formatDiskFree(st.FreeFiles, st.TotalFiles), p.MinFreeInodes)
+	}
+	if p.WarnFree != nil && p.WarnFree.exceeded(st.FreeBytes, st.TotalBytes) {
+		return newWarning("disk %s free space %s is below warn_free %s", p.Path, formatDiskFree(st.FreeBytes, st.TotalBytes), p.WarnFree)
+	}
+	if p.WarnFreeInodes != nil && inodes && p.WarnFreeInodes.exceeded(st.FreeFiles, st.TotalFiles) {
+		return newWarning("disk %s free inodes %s is below warn_free_inodes %s", p.Path, formatDiskFree(st.FreeFiles, st.TotalFiles), p.WarnFreeInodes)
+	}
+	return nil
+}
+
+func diskPercent(free, total uint64) float64 {
+	return percentOf(free, total)
+}
+
+func formatDiskFree(free, total uint64) string {
+	return fmt.Sprintf("%d (%.1f%%)", free, diskPercent(free, total))
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package greenlight
+
+import "errors"
+
+func diskUsage(path string) (*diskStat, error) {
+	return nil, errors.New("disk check is not supported on this platform")
+}
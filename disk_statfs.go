@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+package greenlight
+
+import "syscall"
+
+func diskUsage(path string) (*diskStat, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	bsize := uint64(st.Bsize)
+	return &diskStat{
+		TotalBytes: uint64(st.Blocks) * bsize,
+		FreeBytes:  uint64(st.Bavail) * bsize,
+		TotalFiles: uint64(st.Files),
+		FreeFiles:  uint64(st.Ffree),
+	}, nil
+}
@@ -0,0 +1,56 @@
+//go:build linux || darwin || freebsd
+
+package greenlight_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestDiskChecker(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          greenlight.DiskCheckConfig
+		expectErr    bool
+		expectWarn   bool
+		expectNewErr bool
+	}{
+		{"min_free bytes", greenlight.DiskCheckConfig{MinFree: "1B"}, false, false, false},
+		{"min_free percent", greenlight.DiskCheckConfig{MinFree: "0%"}, false, false, false},
+		{"min_free exceeded", greenlight.DiskCheckConfig{MinFree: "100%"}, true, false, false},
+		{"min_free huge", greenlight.DiskCheckConfig{MinFree: "1024TiB"}, true, false, false},
+		{"warn_free", greenlight.DiskCheckConfig{MinFree: "0%", WarnFree: "100%"}, true, true, false},
+		{"min_free wins over warn_free", greenlight.DiskCheckConfig{MinFree: "100%", WarnFree: "100%"}, true, false, false},
+		{"invalid percent", greenlight.DiskCheckConfig{MinFree: "120%"}, false, false, true},
+		{"invalid inodes", greenlight.DiskCheckConfig{MinFreeInodes: "1GiB"}, false, false, true},
+		{"no thresholds", greenlight.DiskCheckConfig{}, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = t.TempDir()
+			checker, err := greenlight.NewDiskChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				Disk:    &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			var w *greenlight.WarningError
+			if errors.As(err, &w) != tt.expectWarn {
+				t.Errorf("unexpected warning: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package greenlight
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	DefaultDNSResolver = "127.0.0.1:53"
+	resolvConfPath     = "/etc/resolv.conf"
+)
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+type DNSCheckConfig struct {
+	Name          string        `yaml:"name"`
+	Type          string        `yaml:"type"`
+	Resolver      string        `yaml:"resolver"`
+	ExpectAnswers []string      `yaml:"expect_answers"`
+	ExpectPattern string        `yaml:"expect_pattern"`
+	MinRecords    int           `yaml:"min_records"`
+	MaxLatency    time.Duration `yaml:"max_latency"`
+}
+
+type DNSChecker struct {
+	Query         string
+	Type          dnsmessage.Type
+	Resolver      string
+	ExpectAnswers []string
+	ExpectPattern *regexp.Regexp
+	MinRecords    int
+	MaxLatency    time.Duration
+	Timeout       time.Duration
+
+	name string
+}
+
+func (p *DNSChecker) Name() string {
+	return p.name
+}
+
+func NewDNSChecker(cfg *CheckConfig) (*DNSChecker, error) {
+	p := &DNSChecker{
+		name:          cfg.Name,
+		Timeout:       cfg.Timeout,
+		Query:         cfg.DNS.Name,
+		Resolver:      cfg.DNS.Resolver,
+		ExpectAnswers: cfg.DNS.ExpectAnswers,
+		MinRecords:    cfg.DNS.MinRecords,
+		MaxLatency:    cfg.DNS.MaxLatency,
+	}
+	if p.Query == "" {
+		return nil, errors.New("dns name is required")
+	}
+	if !strings.HasSuffix(p.Query, ".") {
+		p.Query += "."
+	}
+	typ := strings.ToUpper(cfg.DNS.Type)
+	if typ == "" {
+		typ = "A"
+	}
+	var ok bool
+	if p.Type, ok = dnsTypes[typ]; !ok {
+		return nil, fmt.Errorf("invalid dns type %s: must be A, AAAA, CNAME, SRV, or TXT", cfg.DNS.Type)
+	}
+	if cfg.DNS.ExpectPattern != "" {
+		pt, err := regexp.Compile(cfg.DNS.ExpectPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+		p.ExpectPattern = pt
+	}
+	// default
+	if p.Resolver == "" {
+		p.Resolver = systemResolver()
+	} else if _, _, err := net.SplitHostPort(p.Resolver); err != nil {
+		p.Resolver = net.JoinHostPort(p.Resolver, "53")
+	}
+	if p.MinRecords == 0 {
+		p.MinRecords = 1
+	}
+	return p, nil
+}
+
+func (p *DNSChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "dnschecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	answers, err := dnsQuery(ctx, p.Resolver, p.Query, p.Type)
+	elapsed := time.Since(start)
+	if err != nil {
+		return fmt.Errorf("dns query %s %s failed: %w", p.Type, p.Query, err)
+	}
+	logger.Debug("resolved",
+		slog.String("query", p.Query),
+		slog.String("type", p.Type.String()),
+		slog.String("resolver", p.Resolver),
+		slog.Any("answers", answers),
+		slog.String("elapsed", elapsed.String()),
+	)
+
+	if len(answers) < p.MinRecords {
+		return fmt.Errorf("dns answers %d are less than min_records %d", len(answers), p.MinRecords)
+	}
+	for _, expect := range p.ExpectAnswers {
+		if !containsAnswer(answers, expect) {
+			return fmt.Errorf("dns answer %s not found in %v", expect, answers)
+		}
+	}
+	if p.ExpectPattern != nil {
+		for _, a := range answers {
+			if !p.ExpectPattern.MatchString(a) {
+				return fmt.Errorf("dns answer %s does not match expect_pattern %s", a, p.ExpectPattern.String())
+			}
+		}
+	}
+	if p.MaxLatency > 0 && elapsed > p.MaxLatency {
+		return fmt.Errorf("dns resolution took %s, exceeds max_latency %s", elapsed, p.MaxLatency)
+	}
+	return nil
+}
+
+func containsAnswer(answers []string, s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	for _, a := range answers {
+		if strings.EqualFold(a, s) {
+			return true
+		}
+		if ip := net.ParseIP(s); ip != nil && ip.Equal(net.ParseIP(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// systemResolver returns the first nameserver in /etc/resolv.conf.
+func systemResolver() string {
+	f, err := os.Open(resolvConfPath)
+	if err != nil {
+		return DefaultDNSResolver
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return DefaultDNSResolver
+}
+
+// dnsQuery sends a query to the resolver by UDP, and retries by TCP if the response is truncated.
+// It returns the answers of the type in text form.
+func dnsQuery(ctx context.Context, resolver, name string, typ dnsmessage.Type) ([]string, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	id := uint16(rand.Intn(1 << 16))
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: typ, Class: dnsmessage.ClassINET}},
+	}
+	req, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	res, err := dnsExchange(ctx, "udp", resolver, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Header.Truncated {
+		if res, err = dnsExchange(ctx, "tcp", resolver, req); err != nil {
+			return nil, err
+		}
+	}
+	if res.Header.ID != id {
+		return nil, errors.New("dns response id mismatch")
+	}
+	if res.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("dns response code %s", res.Header.RCode)
+	}
+
+	var answers []string
+	for _, rr := range res.Answers {
+		if rr.Header.Type != typ {
+			continue // e.g. CNAME chain for A
+		}
+		switch b := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			answers = append(answers, net.IP(b.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			answers = append(answers, net.IP(b.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			answers = append(answers, strings.TrimSuffix(b.CNAME.String(), "."))
+		case *dnsmessage.SRVResource:
+			answers = append(answers, fmt.Sprintf("%d %d %d %s", b.Priority, b.Weight, b.Port, strings.TrimSuffix(b.Target.String(), ".")))
+		case *dnsmessage.TXTResource:
+			answers = append(answers, strings.Join(b.TXT, ""))
+		}
+	}
+	return answers, nil
+}
+
+func dnsExchange(ctx context.Context, network, resolver string, req []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var buf []byte
+	if network == "tcp" {
+		msg := binary.BigEndian.AppendUint16(nil, uint16(len(req)))
+		if _, err := conn.Write(append(msg, req...)); err != nil {
+			return nil, err
+		}
+		var l [2]byte
+		if _, err := io.ReadFull(conn, l[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(l[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+	var res dnsmessage.Message
+	if err := res.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("invalid dns response: %w", err)
+	}
+	return &res, nil
+}
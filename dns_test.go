@@ -0,0 +1,110 @@
+package greenlight_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// runDNSServer runs an in-process DNS server that answers the records.
+func runDNSServer(t *testing.T, records map[string][]dnsmessage.Resource) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			q := req.Questions[0]
+			res := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.Header.ID, Response: true, RecursionAvailable: true},
+				Questions: req.Questions,
+			}
+			rrs, ok := records[q.Name.String()]
+			if !ok {
+				res.Header.RCode = dnsmessage.RCodeNameError
+			}
+			for _, rr := range rrs {
+				if rr.Header.Type == q.Type || rr.Header.Type == dnsmessage.TypeCNAME {
+					rr.Header.Name = q.Name
+					rr.Header.Class = dnsmessage.ClassINET
+					res.Answers = append(res.Answers, rr)
+				}
+			}
+			b, err := res.Pack()
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			conn.WriteTo(b, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSChecker(t *testing.T) {
+	records := map[string][]dnsmessage.Resource{
+		"app.example.com.": {
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA}, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA}, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeAAAA}, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeTXT}, Body: &dnsmessage.TXTResource{TXT: []string{"v=ok"}}},
+		},
+		"www.example.com.": {
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeCNAME}, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("app.example.com.")}},
+		},
+		"_http._tcp.example.com.": {
+			{Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeSRV}, Body: &dnsmessage.SRVResource{Priority: 10, Weight: 5, Port: 8080, Target: dnsmessage.MustNewName("app.example.com.")}},
+		},
+	}
+	resolver := runDNSServer(t, records)
+
+	tests := []struct {
+		name      string
+		cfg       greenlight.DNSCheckConfig
+		expectErr bool
+	}{
+		{"A", greenlight.DNSCheckConfig{Name: "app.example.com", ExpectAnswers: []string{"10.0.0.2"}, MinRecords: 2}, false},
+		{"A pattern", greenlight.DNSCheckConfig{Name: "app.example.com", ExpectPattern: `^10\.0\.0\.\d+$`}, false},
+		{"A too few", greenlight.DNSCheckConfig{Name: "app.example.com", MinRecords: 3}, true},
+		{"A not found", greenlight.DNSCheckConfig{Name: "app.example.com", ExpectAnswers: []string{"10.0.0.3"}}, true},
+		{"AAAA", greenlight.DNSCheckConfig{Name: "app.example.com", Type: "AAAA", ExpectAnswers: []string{"2001:db8::1"}}, false},
+		{"TXT", greenlight.DNSCheckConfig{Name: "app.example.com", Type: "txt", ExpectAnswers: []string{"v=ok"}}, false},
+		{"CNAME", greenlight.DNSCheckConfig{Name: "www.example.com", Type: "CNAME", ExpectAnswers: []string{"app.example.com."}}, false},
+		{"SRV", greenlight.DNSCheckConfig{Name: "_http._tcp.example.com", Type: "SRV", ExpectAnswers: []string{"10 5 8080 app.example.com"}}, false},
+		{"NXDOMAIN", greenlight.DNSCheckConfig{Name: "missing.example.com"}, true},
+		{"no records", greenlight.DNSCheckConfig{Name: "www.example.com", Type: "TXT"}, true},
+		{"latency", greenlight.DNSCheckConfig{Name: "app.example.com", MaxLatency: time.Nanosecond}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Resolver = resolver
+			checker, err := greenlight.NewDNSChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				DNS:     &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
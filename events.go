@@ -0,0 +1,210 @@
+package greenlight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultEventsBufferSize = 16
+	DefaultEventsKeepAlive  = 30 * time.Second
+)
+
+const (
+	eventTypeSignal = "signal"
+	eventTypePhase  = "phase"
+	eventTypeCheck  = "check"
+)
+
+// Event is a state transition streamed by /events.
+type Event struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+type SignalEvent struct {
+	Signal   Signal `json:"signal"`
+	Previous Signal `json:"previous"`
+	Forced   bool   `json:"forced,omitempty"`
+}
+
+type PhaseEvent struct {
+	Phase    phase `json:"phase"`
+	Previous phase `json:"previous"`
+}
+
+type CheckEvent struct {
+	Phase   phase          `json:"phase"`
+	Index   int            `json:"index"`
+	Name    string         `json:"name"`
+	OK      bool           `json:"ok"`
+	Warning bool           `json:"warning,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type checkDetailsKeyType string
+
+const (
+	checkDetailsKey checkDetailsKeyType = "checkDetails"
+)
+
+// checkDetails collects the details of a check run attached to the check event, e.g. the timings of http check.
+type checkDetails struct {
+	mu sync.Mutex
+	m  map[string]any
+}
+
+// withCheckDetails returns a context to collect the details of a check run.
+func withCheckDetails(ctx context.Context) (context.Context, *checkDetails) {
+	d := &checkDetails{}
+	return context.WithValue(ctx, checkDetailsKey, d), d
+}
+
+// setCheckDetail sets a detail of the check run. It does nothing out of the startup and readiness checks.
+func setCheckDetail(ctx context.Context, key string, value any) {
+	d, ok := ctx.Value(checkDetailsKey).(*checkDetails)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.m == nil {
+		d.m = make(map[string]any)
+	}
+	d.m[key] = value
+}
+
+func (d *checkDetails) get() map[string]any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.m
+}
+
+// eventHub broadcasts events to subscribers.
+// publish never blocks. A subscriber that can't keep up is disconnected.
+type eventHub struct {
+	mu     sync.Mutex
+	seq    uint64
+	subs   map[chan Event]struct{}
+	last   map[string]Event // latest events replayed to new subscribers
+	order  []string
+	closed bool
+	logger *slog.Logger
+}
+
+func newEventHub(logger *slog.Logger) *eventHub {
+	return &eventHub{
+		subs:   make(map[chan Event]struct{}),
+		last:   make(map[string]Event),
+		logger: logger,
+	}
+}
+
+func (h *eventHub) publish(typ, key string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.seq++
+	ev := Event{ID: h.seq, Type: typ, Time: time.Now(), Data: data}
+	if _, ok := h.last[key]; !ok {
+		h.order = append(h.order, key)
+	}
+	h.last[key] = ev
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			h.logger.Warn("disconnecting slow events subscriber")
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns a channel that receives the latest state events first, and then new events.
+func (h *eventHub) subscribe() chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan Event, DefaultEventsBufferSize+len(h.order))
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	for _, key := range h.order {
+		ch <- h.last[key]
+	}
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming Unsupported", http.StatusInternalServerError)
+			return
+		}
+		h.logger.Info("events subscriber connected", slog.String("remote_addr", req.RemoteAddr))
+		defer h.logger.Info("events subscriber disconnected", slog.String("remote_addr", req.RemoteAddr))
+
+		ch := h.subscribe()
+		defer h.unsubscribe(ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Server", "greenlight/"+Version)
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(DefaultEventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+			case ev, ok := <-ch:
+				if !ok {
+					return
+				}
+				b, err := json.Marshal(ev)
+				if err != nil {
+					h.logger.Error("failed to marshal event", slog.String("error", err.Error()))
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, b)
+			}
+			flusher.Flush()
+		}
+	})
+}
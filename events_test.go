@@ -0,0 +1,250 @@
+package greenlight_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestEventHubReplay(t *testing.T) {
+	h := greenlight.NewEventHub()
+	h.Publish("phase", "phase", greenlight.PhaseEvent{Phase: "running", Previous: "startup"})
+	h.Publish("signal", "signal", greenlight.SignalEvent{Signal: greenlight.SignalYellow})
+	h.Publish("signal", "signal", greenlight.SignalEvent{Signal: greenlight.SignalGreen, Previous: greenlight.SignalYellow})
+
+	// the latest event of each key is replayed in the order of the first publish
+	ch := h.Subscribe()
+	for _, expect := range []struct {
+		id  uint64
+		typ string
+	}{{1, "phase"}, {3, "signal"}} {
+		ev := <-ch
+		if ev.ID != expect.id || ev.Type != expect.typ {
+			t.Errorf("unexpected replayed event: %#v", ev)
+		}
+	}
+	h.Publish("check", "check/running/0", greenlight.CheckEvent{Name: "web", OK: true})
+	if ev := <-ch; ev.ID != 4 || ev.Type != "check" {
+		t.Errorf("unexpected event: %#v", ev)
+	}
+
+	h.Close()
+	if _, ok := <-ch; ok {
+		t.Error("subscriber must be closed")
+	}
+	if _, ok := <-h.Subscribe(); ok {
+		t.Error("subscribe after close must return a closed channel")
+	}
+}
+
+func TestEventHubSlowSubscriber(t *testing.T) {
+	h := greenlight.NewEventHub()
+	slow := h.Subscribe()
+	for i := 0; i <= greenlight.DefaultEventsBufferSize; i++ {
+		h.Publish("signal", "signal", greenlight.SignalEvent{Signal: greenlight.SignalGreen})
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != greenlight.DefaultEventsBufferSize {
+		t.Errorf("slow subscriber must be disconnected after %d events: %d", greenlight.DefaultEventsBufferSize, n)
+	}
+
+	// publish never blocks, and other subscribers keep receiving
+	ch := h.Subscribe()
+	h.Publish("phase", "phase", greenlight.PhaseEvent{Phase: "running"})
+	<-ch // replayed
+	if ev := <-ch; ev.Type != "phase" {
+		t.Errorf("unexpected event: %#v", ev)
+	}
+}
+
+func TestEventHubHandler(t *testing.T) {
+	h := greenlight.NewEventHub()
+	h.Publish("signal", "signal", greenlight.SignalEvent{Signal: greenlight.SignalGreen})
+	ts := httptest.NewServer(h.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() []string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				return lines
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	lines := readEvent()
+	if len(lines) != 3 || lines[0] != "id: 1" || lines[1] != "event: signal" ||
+		!strings.HasPrefix(lines[2], `data: {"id":1,"type":"signal",`) ||
+		!strings.HasSuffix(lines[2], `"data":{"signal":"green","previous":""}}`) {
+		t.Errorf("unexpected replayed event: %q", lines)
+	}
+
+	h.Publish("check", "check/running/0", greenlight.CheckEvent{Phase: "running", Name: "web", Error: "failed"})
+	lines = readEvent()
+	if len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: check" ||
+		!strings.HasSuffix(lines[2], `"data":{"phase":"running","index":0,"name":"web","ok":false,"error":"failed"}}`) {
+		t.Errorf("unexpected event: %q", lines)
+	}
+
+	h.Close()
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Error("stream must be closed")
+	}
+}
+
+func TestSignalEventsForced(t *testing.T) {
+	g := newTestGreenlight(t, &greenlight.Config{})
+	g.SetSignal(greenlight.SignalGreen)
+	ch := g.SubscribeEvents()
+	next := func() greenlight.SignalEvent {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev.Data.(greenlight.SignalEvent)
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		return greenlight.SignalEvent{}
+	}
+	if ev := next(); ev.Signal != greenlight.SignalGreen {
+		t.Errorf("unexpected replayed event: %#v", ev)
+	}
+
+	admin := g.AdminHandler()
+	adminRequest(t, admin, http.MethodPost, "/admin/signal?signal=red&duration=200ms", "admin-secret")
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalRed, Previous: greenlight.SignalGreen, Forced: true}) {
+		t.Errorf("unexpected event on force: %#v", ev)
+	}
+	// the underlying signal changes while forced, the effective signal does not
+	g.SetSignal(greenlight.SignalYellow)
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalYellow, Previous: greenlight.SignalRed}) {
+		t.Errorf("unexpected event on expiry: %#v", ev)
+	}
+
+	adminRequest(t, admin, http.MethodPost, "/admin/signal?signal=green", "admin-secret")
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalGreen, Previous: greenlight.SignalYellow, Forced: true}) {
+		t.Errorf("unexpected event on force: %#v", ev)
+	}
+	adminRequest(t, admin, http.MethodDelete, "/admin/signal", "admin-secret")
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalYellow, Previous: greenlight.SignalGreen}) {
+		t.Errorf("unexpected event on clear: %#v", ev)
+	}
+
+	// clearing a forced signal same as the underlying one is not a transition
+	adminRequest(t, admin, http.MethodPost, "/admin/signal?signal=yellow", "admin-secret")
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalYellow, Previous: greenlight.SignalYellow, Forced: true}) {
+		t.Errorf("unexpected event on force: %#v", ev)
+	}
+	adminRequest(t, admin, http.MethodDelete, "/admin/signal", "admin-secret")
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event on clear without change: %#v", ev.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+	g.SetSignal(greenlight.SignalGreen)
+	if ev := next(); ev != (greenlight.SignalEvent{Signal: greenlight.SignalGreen, Previous: greenlight.SignalYellow}) {
+		t.Errorf("unexpected event on change: %#v", ev)
+	}
+}
+
+func TestCheckEventTimings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer ts.Close()
+	g := newTestGreenlight(t, &greenlight.Config{
+		Readiness: &greenlight.PhaseConfig{
+			Checks: []*greenlight.CheckConfig{
+				{Name: "ok", Timeout: time.Second, HTTP: &greenlight.HTTPCheckConfig{URL: ts.URL}},
+				{Name: "error", Timeout: time.Second, HTTP: &greenlight.HTTPCheckConfig{URL: ts.URL + "/error"}},
+				{Name: "refused", Timeout: time.Second, HTTP: &greenlight.HTTPCheckConfig{URL: "http://127.0.0.1:1"}},
+				{Name: "command", Timeout: time.Second, Command: &greenlight.CommandCheckConfig{Run: "true"}},
+			},
+		},
+	})
+	ch := g.SubscribeEvents()
+	if err := g.CheckRediness(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, expect := range []struct {
+		name    string
+		ok      bool
+		timings bool
+	}{
+		{"ok", true, true},
+		{"error", false, true},
+		{"refused", false, true},
+		{"command", true, false},
+	} {
+		var ev greenlight.CheckEvent
+		select {
+		case e := <-ch:
+			ev = e.Data.(greenlight.CheckEvent)
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		if ev.Name != expect.name || ev.OK != expect.ok {
+			t.Errorf("unexpected event: %#v", ev)
+		}
+		b, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Details struct {
+				Timings map[string]string `json:"timings"`
+			} `json:"details"`
+		}
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		timings := decoded.Details.Timings
+		if !expect.timings {
+			if timings != nil {
+				t.Errorf("%s: unexpected timings: %s", expect.name, b)
+			}
+			continue
+		}
+		total, err := time.ParseDuration(timings["total"])
+		if err != nil || total <= 0 {
+			t.Errorf("%s: invalid total of timings: %s", expect.name, b)
+		}
+		for _, k := range []string{"dns", "connect", "tls", "ttfb"} {
+			if _, err := time.ParseDuration(timings[k]); err != nil {
+				t.Errorf("%s: invalid %s of timings: %s", expect.name, k, b)
+			}
+		}
+	}
+}
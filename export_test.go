@@ -1,5 +1,96 @@
 package greenlight
 
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
 var (
 	NewExpectCodeFunc = newExpectCodeFunc
+	ScramHi           = scramHi
+)
+
+var (
+	RedactConfig = redactConfig
+)
+
+func NewResultCacheFunc(ttl time.Duration, f func(context.Context) error) func(context.Context) error {
+	c := newResultCache(ttl)
+	return func(ctx context.Context) error {
+		return c.do(ctx, func() error {
+			return f(context.Background())
+		})
+	}
+}
+
+func (g *Greenlight) AdminHandler() http.Handler {
+	return g.admin.handler()
+}
+
+func (g *Greenlight) ResponderHandler() http.Handler {
+	return g.responder.handler()
+}
+
+func (g *Greenlight) SetSignal(s Signal) {
+	g.responder.setCurrentSignal(s)
+}
+
+func NewEventHub() *eventHub {
+	return newEventHub(slog.Default())
+}
+
+func (h *eventHub) Publish(typ, key string, data any) {
+	h.publish(typ, key, data)
+}
+
+func (h *eventHub) Subscribe() chan Event {
+	return h.subscribe()
+}
+
+func (h *eventHub) Close() {
+	h.close()
+}
+
+func (h *eventHub) Handler() http.Handler {
+	return h.handler()
+}
+
+func (g *Greenlight) SubscribeEvents() chan Event {
+	return g.responder.events.subscribe()
+}
+
+func (g *Greenlight) Signals() chan Signal {
+	return g.ch
+}
+
+func (g *Greenlight) OnDemandSignal(ctx, req context.Context) Signal {
+	return g.onDemandSignal(ctx, req)
+}
+
+func (g *Greenlight) SetClock(now func() time.Time) {
+	g.responder.now = now
+}
+
+var ClockTicks = clockTicks
+
+func (g *Greenlight) SetChildCommand(cmds []string) {
+	g.childCmds = cmds
+}
+
+func (g *Greenlight) ChildPID() int {
+	return g.state.ChildPID()
+}
+
+func (g *Greenlight) WithState(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stateKey, g.state)
+}
+
+var (
+	ReadCPUTimes    = readCPUTimes
+	CPUStealPercent = cpuStealPercent
+
+	ReadFlatKeyed       = readFlatKeyed
+	CPUThrottledPercent = cpuThrottledPercent
 )
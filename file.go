@@ -0,0 +1,260 @@
+package greenlight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultFileMaxBytes = int64(1024 * 1024)
+)
+
+var fileTypes = map[string]fs.FileMode{
+	"file":      0,
+	"directory": fs.ModeDir,
+	"symlink":   fs.ModeSymlink,
+	"socket":    fs.ModeSocket,
+	"pipe":      fs.ModeNamedPipe,
+}
+
+type FileCheckConfig struct {
+	Path           string        `yaml:"path"`
+	Exists         *bool         `yaml:"exists"`
+	Type           string        `yaml:"type"`
+	Mode           FileModeValue `yaml:"mode"`
+	Owner          string        `yaml:"owner"`
+	MinSize        *int64        `yaml:"min_size"`
+	MaxSize        *int64        `yaml:"max_size"`
+	MaxAge         time.Duration `yaml:"max_age"`
+	ContentPattern string        `yaml:"content_pattern"`
+	MaxBytes       int64         `yaml:"max_bytes"`
+}
+
+// FileModeValue is an octal file mode like "0644".
+// An unquoted YAML value (0644) is kept as written, not decoded as an integer.
+type FileModeValue string
+
+func (v *FileModeValue) UnmarshalYAML(b []byte) error {
+	*v = FileModeValue(strings.Trim(strings.TrimSpace(string(b)), `"'`))
+	return nil
+}
+
+type FileChecker struct {
+	Path           string
+	Absent         bool
+	Type           *fs.FileMode
+	Mode           *uint32 // octal like 0644
+	OwnerUID       *int
+	MinSize        *int64
+	MaxSize        *int64
+	MaxAge         time.Duration
+	ContentPattern *regexp.Regexp
+	MaxBytes       int64
+	Timeout        time.Duration
+
+	name string
+}
+
+func (p *FileChecker) Name() string {
+	return p.name
+}
+
+func NewFileChecker(cfg *CheckConfig) (*FileChecker, error) {
+	c := cfg.File
+	p := &FileChecker{
+		name:     cfg.Name,
+		Path:     c.Path,
+		MinSize:  c.MinSize,
+		MaxSize:  c.MaxSize,
+		MaxAge:   c.MaxAge,
+		MaxBytes: c.MaxBytes,
+		Timeout:  cfg.Timeout,
+	}
+	if p.Path == "" {
+		return nil, errors.New("file path is required")
+	}
+	if c.Exists != nil && !*c.Exists {
+		p.Absent = true
+		return p, nil // other assertions are meaningless for an absent file
+	}
+	if c.Type != "" {
+		t, ok := fileTypes[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("invalid type %s: must be file, directory, symlink, socket, or pipe", c.Type)
+		}
+		p.Type = &t
+	}
+	if c.Mode != "" {
+		m, err := strconv.ParseUint(strings.TrimPrefix(string(c.Mode), "0o"), 8, 32) // YAML 1.2 style 0o644 is also accepted
+		if err != nil || m > 0o7777 {
+			return nil, fmt.Errorf("invalid mode %s: must be octal like 0644", c.Mode)
+		}
+		mode := uint32(m)
+		p.Mode = &mode
+	}
+	if c.Owner != "" {
+		uid, err := strconv.Atoi(c.Owner)
+		if err != nil {
+			u, err := user.Lookup(c.Owner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid owner %s: %w", c.Owner, err)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return nil, fmt.Errorf("invalid owner %s: %w", c.Owner, err)
+			}
+		}
+		p.OwnerUID = &uid
+	}
+	if c.ContentPattern != "" {
+		pt, err := regexp.Compile(c.ContentPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content_pattern: %w", err)
+		}
+		p.ContentPattern = pt
+	}
+	if p.MaxBytes == 0 {
+		p.MaxBytes = DefaultFileMaxBytes
+	}
+	return p, nil
+}
+
+func (p *FileChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "filechecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	// symlink itself is checked for type: symlink.
+	stat := os.Stat
+	if p.Type != nil && *p.Type == fs.ModeSymlink {
+		stat = os.Lstat
+	}
+	fi, err := stat(p.Path)
+	if p.Absent {
+		if err == nil {
+			return fmt.Errorf("file %s exists", p.Path)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("file stat failed: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("file stat failed: %w", err)
+	}
+	logger.Debug(fmt.Sprintf("%s mode=%s size=%d mtime=%s", p.Path, fi.Mode(), fi.Size(), fi.ModTime().Format(time.RFC3339)))
+
+	if p.Type != nil && fi.Mode().Type() != *p.Type {
+		return fmt.Errorf("file %s type is %s, expected %s", p.Path, fileTypeName(fi.Mode()), fileTypeName(*p.Type))
+	}
+	if p.Mode != nil {
+		if m := fileModeOctal(fi.Mode()); m != *p.Mode {
+			return fmt.Errorf("file %s mode is %04o, expected %04o", p.Path, m, *p.Mode)
+		}
+	}
+	if p.OwnerUID != nil {
+		uid, ok := fileOwner(fi)
+		if !ok {
+			return errors.New("file owner is not supported on this platform")
+		}
+		if uid != *p.OwnerUID {
+			return fmt.Errorf("file %s owner uid is %d, expected %d", p.Path, uid, *p.OwnerUID)
+		}
+	}
+	if p.MinSize != nil && fi.Size() < *p.MinSize {
+		return fmt.Errorf("file %s size %d is less than min_size %d", p.Path, fi.Size(), *p.MinSize)
+	}
+	if p.MaxSize != nil && fi.Size() > *p.MaxSize {
+		return fmt.Errorf("file %s size %d is greater than max_size %d", p.Path, fi.Size(), *p.MaxSize)
+	}
+	if p.MaxAge > 0 {
+		if age := time.Since(fi.ModTime()); age > p.MaxAge {
+			return fmt.Errorf("file %s is not modified for %s, exceeds max_age %s", p.Path, age.Truncate(time.Second), p.MaxAge)
+		}
+	}
+	if p.ContentPattern != nil {
+		b, err := p.readContent(ctx)
+		if err != nil {
+			return err
+		}
+		if !p.ContentPattern.Match(b) {
+			return fmt.Errorf("file %s content does not match content_pattern %s", p.Path, p.ContentPattern.String())
+		}
+	}
+	return nil
+}
+
+// readContent reads the first MaxBytes of the file.
+// A slow file system (e.g. a hung NFS mount) can block the read, so it runs in
+// a goroutine bounded by the context.
+func (p *FileChecker) readContent(ctx context.Context) ([]byte, error) {
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		b, err := readRegularFile(p.Path, p.MaxBytes)
+		ch <- result{b, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.b, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("file %s read timed out: %w", p.Path, ctx.Err())
+	}
+}
+
+func readRegularFile(path string, maxBytes int64) ([]byte, error) {
+	// opening a named pipe blocks until a writer appears, and reading a device
+	// may never end. content_pattern is only for regular files.
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file stat failed: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("file %s type is %s, content_pattern requires a regular file", path, fileTypeName(fi.Mode()))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("file open failed: %w", err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, maxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("file read failed: %w", err)
+	}
+	return b, nil
+}
+
+// fileModeOctal converts a FileMode to the Unix octal notation like 04755.
+func fileModeOctal(m fs.FileMode) uint32 {
+	o := uint32(m.Perm())
+	if m&fs.ModeSetuid != 0 {
+		o |= 0o4000
+	}
+	if m&fs.ModeSetgid != 0 {
+		o |= 0o2000
+	}
+	if m&fs.ModeSticky != 0 {
+		o |= 0o1000
+	}
+	return o
+}
+
+func fileTypeName(m fs.FileMode) string {
+	for name, t := range fileTypes {
+		if m.Type() == t {
+			return name
+		}
+	}
+	return m.Type().String()
+}
@@ -0,0 +1,129 @@
+//go:build !windows
+
+package greenlight_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+	"github.com/goccy/go-yaml"
+)
+
+func TestFileChecker(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "heartbeat")
+	if err := os.WriteFile(path, []byte("ok\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "maintenance")
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		t.Fatal(err)
+	}
+	me, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := os.Getuid()
+
+	tests := []struct {
+		name         string
+		config       string // YAML of the file section
+		expectNewErr bool
+		expectErr    bool
+	}{
+		{"exists", "path: " + path, false, false},
+		{"not exists", "path: " + missing, false, true},
+		{"mode unquoted", "path: " + path + "\nmode: 0640", false, false},
+		{"mode quoted", "path: " + path + "\nmode: \"0640\"", false, false},
+		{"mode single quoted", "path: " + path + "\nmode: '640'", false, false},
+		{"mode yaml 1.2", "path: " + path + "\nmode: 0o640", false, false},
+		{"mode mismatch", "path: " + path + "\nmode: 0644", false, true},
+		{"mode invalid octal", "path: " + path + "\nmode: 0999", true, false},
+		{"mode too large", "path: " + path + "\nmode: 017777", true, false},
+		{"mode symbolic", "path: " + path + "\nmode: rw-r-----", true, false},
+		{"owner uid", "path: " + path + "\nowner: \"" + strconv.Itoa(uid) + "\"", false, false},
+		{"owner name", "path: " + path + "\nowner: " + me.Username, false, false},
+		{"owner mismatch", "path: " + path + "\nowner: \"" + strconv.Itoa(uid+1) + "\"", false, true},
+		{"owner unknown", "path: " + path + "\nowner: no-such-user-greenlight", true, false},
+		{"max_age", "path: " + path + "\nmax_age: 3h", false, false},
+		{"max_age exceeded", "path: " + path + "\nmax_age: 1h", false, true},
+		{"type file", "path: " + path + "\ntype: file", false, false},
+		{"type directory", "path: " + dir + "\ntype: directory", false, false},
+		{"type mismatch", "path: " + dir + "\ntype: file", false, true},
+		{"type symlink", "path: " + link + "\ntype: symlink", false, false},
+		{"type follows symlink", "path: " + link + "\ntype: file", false, false},
+		{"type invalid", "path: " + path + "\ntype: device", true, false},
+		{"size", "path: " + path + "\nmin_size: 1\nmax_size: 3", false, false},
+		{"size too small", "path: " + path + "\nmin_size: 4", false, true},
+		{"content_pattern", "path: " + path + "\ncontent_pattern: ^ok", false, false},
+		{"content_pattern not match", "path: " + path + "\ncontent_pattern: ^ng", false, true},
+		{"content_pattern symlink", "path: " + link + "\ncontent_pattern: ^ok", false, false},
+		{"content_pattern directory", "path: " + dir + "\ncontent_pattern: ^ok", false, true},
+		{"content_pattern pipe", "path: " + fifo + "\ncontent_pattern: ^ok", false, true},
+		{"type pipe", "path: " + fifo + "\ntype: pipe", false, false},
+		{"exists false", "path: " + missing + "\nexists: false", false, false},
+		{"exists false but exists", "path: " + path + "\nexists: false", false, true},
+		{"exists false ignores others", "path: " + missing + "\nexists: false\nmode: 0644\nmax_age: 1s", false, false},
+		{"exists true", "path: " + path + "\nexists: true", false, false},
+		{"path required", "mode: 0644", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg greenlight.FileCheckConfig
+			if err := yaml.Unmarshal([]byte(tt.config), &cfg); err != nil {
+				t.Fatal(err)
+			}
+			checker, err := greenlight.NewFileChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				File:    &cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("expected new error: %v, got: %v", tt.expectNewErr, err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestFileModeValue(t *testing.T) {
+	for _, s := range []string{"0644", "\"0644\"", "'0644'", "644"} {
+		var cfg greenlight.FileCheckConfig
+		if err := yaml.Unmarshal([]byte(fmt.Sprintf("mode: %s", s)), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		want := greenlight.FileModeValue(s)
+		if s[0] == '"' || s[0] == '\'' {
+			want = greenlight.FileModeValue(s[1 : len(s)-1])
+		}
+		if cfg.Mode != want {
+			t.Errorf("mode %s is decoded as %q, expected %q", s, cfg.Mode, want)
+		}
+	}
+}
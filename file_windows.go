@@ -0,0 +1,7 @@
+package greenlight
+
+import "io/fs"
+
+func fileOwner(fi fs.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -23,8 +24,17 @@ type Greenlight struct {
 	startUpChecks   []Checker
 	readinessChecks []Checker
 	responder       *Responder
+	admin           *Admin
 	ch              chan Signal
+	trigger         chan struct{}
+	skip            chan struct{}
 	childCmds       []string
+	cache           *resultCache
+	lastCompleted   atomic.Int64 // unix nano of the last completed readiness checks
+	stale           atomic.Bool  // the watchdog found the readiness results stale
+
+	resultsMu sync.Mutex
+	results   map[string]CheckEvent
 }
 
 func Run(ctx context.Context, cli *CLI) error {
@@ -61,6 +71,19 @@ func NewGreenlight(cfg *Config) (*Greenlight, error) {
 		state:     newState(),
 		responder: responder,
 		ch:        ch,
+		trigger:   make(chan struct{}, 1),
+		skip:      make(chan struct{}, 1),
+		results:   make(map[string]CheckEvent),
+	}
+	if cfg.Responder.OnDemand != nil {
+		g.cache = newResultCache(cfg.Responder.OnDemand.TTL)
+	}
+	if cfg.Responder.Admin != nil {
+		admin, err := NewAdmin(cfg.Responder.Admin, g)
+		if err != nil {
+			return nil, err
+		}
+		g.admin = admin
 	}
 	for _, c := range cfg.StartUp.Checks {
 		checker, err := NewChecker(c)
@@ -87,6 +110,13 @@ func (g *Greenlight) Run(ctx context.Context) error {
 	wg.Add(1)
 	go g.RunChildCommand(ctx, wg, childCommandErr)
 
+	// Run admin API. (optional)
+	adminErr := make(chan error, 1)
+	if g.admin != nil {
+		wg.Add(1)
+		go g.RunAdmin(ctx, wg, adminErr)
+	}
+
 	// Run startup checks.
 	startUpErr := make(chan error, 1)
 	wg.Add(1)
@@ -100,6 +130,8 @@ func (g *Greenlight) Run(ctx context.Context) error {
 		}
 	case err := <-childCommandErr:
 		return err
+	case err := <-adminErr:
+		return err
 	}
 
 	// StartUp succeeded. Signal green.
@@ -110,10 +142,16 @@ func (g *Greenlight) Run(ctx context.Context) error {
 	wg.Add(1)
 	go g.RunResponder(ctx, wg, responderErr)
 
-	// Run readiness checks.
+	// Run readiness checks. (periodic unless on-demand only)
 	redinessErr := make(chan error, 1)
-	wg.Add(1)
-	go g.RunRedinessChecks(ctx, wg, redinessErr)
+	if od := g.Config.Responder.OnDemand; od == nil || od.Periodic {
+		wg.Add(1)
+		go g.RunRedinessChecks(ctx, wg, redinessErr)
+		if g.Config.Readiness.StaleFactor > 0 {
+			wg.Add(1)
+			go g.RunStaleWatchdog(ctx, wg)
+		}
+	}
 
 	// Wait for readiness checks or responder or child command.
 	select {
@@ -127,6 +165,10 @@ func (g *Greenlight) Run(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+	case err := <-adminErr:
+		if err != nil {
+			return err
+		}
 	}
 
 	wg.Wait()
@@ -143,7 +185,14 @@ func (g *Greenlight) RunStartUpChecks(ctx context.Context, wg *sync.WaitGroup, c
 	logger.Info("starting checks for startup")
 	if t := g.Config.StartUp.GracePeriod; t > 0 {
 		logger.Info(fmt.Sprintf("sleeping grace period %s", t))
-		time.Sleep(t)
+		select {
+		case <-time.After(t):
+		case <-g.skip:
+			logger.Warn("skipping grace period by admin request")
+		case <-ctx.Done():
+			ch <- nil
+			return
+		}
 	}
 	for {
 		select {
@@ -159,10 +208,19 @@ func (g *Greenlight) RunStartUpChecks(ctx context.Context, wg *sync.WaitGroup, c
 				slog.String("name", g.startUpChecks[g.state.CheckIndex].Name()),
 				slog.String("error", err.Error()))
 			logger.Info(fmt.Sprintf("sleeping %s", g.Config.StartUp.Interval))
-			time.Sleep(g.Config.StartUp.Interval)
+			select {
+			case <-time.After(g.Config.StartUp.Interval):
+			case <-g.skip:
+				logger.Warn("skipping check by admin request",
+					slog.Int("index", int(g.state.CheckIndex)),
+					slog.String("name", g.startUpChecks[g.state.CheckIndex].Name()))
+				g.state.setCheckIndex(g.state.CheckIndex + 1)
+			case <-ctx.Done():
+			}
 			continue
 		}
 		g.state.NextPhase()
+		g.responder.publish(eventTypePhase, eventTypePhase, PhaseEvent{Phase: phaseRunning, Previous: phaseStartUp})
 		logger.Info("all checks succeeded! go to next phase")
 		ch <- nil
 		return
@@ -172,10 +230,26 @@ func (g *Greenlight) RunStartUpChecks(ctx context.Context, wg *sync.WaitGroup, c
 func (g *Greenlight) CheckStartUp(ctx context.Context) error {
 	ctx = context.WithValue(ctx, stateKey, g.state)
 	for i := g.state.CheckIndex; i < numofCheckers(len(g.startUpChecks)); i++ {
-		g.state.CheckIndex = numofCheckers(i)
+		g.state.setCheckIndex(i)
 		check := g.startUpChecks[i]
 		now := time.Now()
-		if err := check.Run(ctx); err != nil {
+		checkCtx, details := withCheckDetails(ctx)
+		skipped, err := g.runStartUpCheck(checkCtx, check)
+		if skipped {
+			slog.Warn("skipping check by admin request",
+				slog.Int("index", int(i)), slog.String("name", check.Name()))
+			continue
+		}
+		g.recordResult(phaseStartUp, int(i), check, err, details.get())
+		if isWarning(err) {
+			// a degraded check does not block the startup.
+			slog.Warn("check degraded",
+				slog.Int("index", int(i)), slog.String("name", check.Name()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if err != nil {
 			return err
 		}
 		elapsed := time.Since(now)
@@ -187,6 +261,23 @@ func (g *Greenlight) CheckStartUp(ctx context.Context) error {
 	return nil
 }
 
+// runStartUpCheck runs the check until it returns or a skip is requested.
+// A skipped check is canceled, and its result is discarded.
+func (g *Greenlight) runStartUpCheck(ctx context.Context, check Checker) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		return false, err
+	case <-g.skip:
+		return true, nil
+	}
+}
+
 func (g *Greenlight) RunRedinessChecks(ctx context.Context, wg *sync.WaitGroup, ch chan error) {
 	defer wg.Done()
 	logger := slog.With("phase", g.state.Phase)
@@ -196,16 +287,21 @@ func (g *Greenlight) RunRedinessChecks(ctx context.Context, wg *sync.WaitGroup,
 		time.Sleep(t)
 	}
 	for {
-		err := g.CheckRediness(ctx)
-		if err != nil {
-			logger.Warn("some checks failed", slog.String("error", err.Error()))
+		err := g.evaluateRediness(ctx, ctx)
+		if isWarning(err) {
+			logger.Warn("some checks degraded", slog.String("error", err.Error()))
 			g.Send(SignalYellow)
+		} else if err != nil {
+			logger.Warn("some checks failed", slog.String("error", err.Error()))
+			g.Send(SignalRed)
 		} else {
 			logger.Debug("all checks succeeded!")
 			g.Send(SignalGreen)
 		}
 		select {
 		case <-time.After(g.Config.Readiness.Interval):
+		case <-g.trigger:
+			logger.Info("readiness checks triggered by admin request")
 		case <-ctx.Done():
 			ch <- nil
 			return
@@ -213,16 +309,59 @@ func (g *Greenlight) RunRedinessChecks(ctx context.Context, wg *sync.WaitGroup,
 	}
 }
 
+// evaluateRediness runs the readiness checks with ctx, or returns the cached result in on-demand mode.
+// In on-demand mode, the caller waits for the result until wait is done.
+func (g *Greenlight) evaluateRediness(ctx, wait context.Context) error {
+	if g.cache == nil {
+		return g.CheckRediness(ctx)
+	}
+	return g.cache.do(wait, func() error {
+		return g.CheckRediness(ctx)
+	})
+}
+
+// onDemandSignal evaluates the signal for a health request.
+// The readiness checks run with ctx, and the request waits for the result until req is done or the on-demand timeout.
+func (g *Greenlight) onDemandSignal(ctx, req context.Context) Signal {
+	logger := slog.With("phase", phaseRunning, "module", "ondemand")
+	if g.stale.Load() {
+		// the watchdog signals red, the cached result must not override it.
+		logger.Debug("readiness check results are stale")
+		return SignalRed
+	}
+	timeout := g.Config.Responder.OnDemand.Timeout
+	if timeout == 0 {
+		timeout = DefaultOnDemandTimeout
+	}
+	wait, cancel := context.WithTimeout(req, timeout)
+	defer cancel()
+	err := g.evaluateRediness(ctx, wait)
+	if isWarning(err) {
+		logger.Debug("some checks degraded", slog.String("error", err.Error()))
+		return SignalYellow
+	} else if err != nil {
+		logger.Debug("some checks failed", slog.String("error", err.Error()))
+		return SignalRed
+	}
+	return SignalGreen
+}
+
 func (g *Greenlight) CheckRediness(ctx context.Context) error {
 	ctx = context.WithValue(ctx, stateKey, g.state)
 	logger := slog.With("phase", g.state.Phase)
 
 	var errs error
+	defer func() {
+		g.lastCompleted.Store(time.Now().UnixNano())
+	}()
 	// rediness checks allways run all.
 	for i, check := range g.readinessChecks {
-		g.state.CheckIndex = numofCheckers(i)
+		g.state.setCheckIndex(numofCheckers(i))
 		now := time.Now()
-		if err := check.Run(ctx); err != nil {
+		checkCtx, details := withCheckDetails(ctx)
+		err := check.Run(checkCtx)
+		g.recordResult(phaseRunning, i, check, err, details.get())
+		if err != nil {
 			errs = errors.Join(errs, fmt.Errorf("check %d failed: %w", i, err))
 		}
 		elapsed := time.Since(now)
@@ -234,13 +373,107 @@ func (g *Greenlight) CheckRediness(ctx context.Context) error {
 	return errs
 }
 
+// recordResult publishes a check event when the result of the check changed.
+// The details of the run (e.g. timings of http check) are of the run that changed the result.
+func (g *Greenlight) recordResult(p phase, i int, check Checker, err error, details map[string]any) {
+	ev := CheckEvent{Phase: p, Index: i, Name: check.Name(), OK: err == nil, Warning: isWarning(err), Details: details}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	key := fmt.Sprintf("%s/%s/%d", eventTypeCheck, p, i)
+
+	g.resultsMu.Lock()
+	defer g.resultsMu.Unlock()
+	if prev, ok := g.results[key]; ok && prev.OK == ev.OK && prev.Warning == ev.Warning {
+		return
+	}
+	g.results[key] = ev
+	g.responder.publish(eventTypeCheck, key, ev)
+}
+
+// RunStaleWatchdog signals red when the readiness checks have not completed
+// for readiness.stale_factor * readiness.interval, e.g. a check hangs.
+func (g *Greenlight) RunStaleWatchdog(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	interval := g.Config.Readiness.Interval
+	threshold := time.Duration(g.Config.Readiness.StaleFactor * float64(interval))
+	logger := slog.With("phase", phaseRunning, "module", "watchdog")
+	logger.Info(fmt.Sprintf("starting stale watchdog threshold %s", threshold))
+
+	// The grace period is not counted as stale.
+	g.lastCompleted.CompareAndSwap(0, time.Now().Add(g.Config.Readiness.GracePeriod).UnixNano())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		last := time.Unix(0, g.lastCompleted.Load())
+		elapsed := time.Since(last)
+		if elapsed <= threshold {
+			if g.stale.Swap(false) {
+				logger.Info("readiness check results are fresh again")
+			}
+			continue
+		}
+		g.stale.Store(true)
+		logger.Error("readiness check results are stale",
+			slog.String("last_completed", last.Format(time.RFC3339Nano)),
+			slog.String("elapsed", elapsed.String()),
+			slog.String("threshold", threshold.String()),
+		)
+		select {
+		case g.ch <- SignalRed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (g *Greenlight) RunResponder(ctx context.Context, wg *sync.WaitGroup, ch chan error) {
 	defer wg.Done()
+	if g.cache != nil {
+		g.responder.SetEvaluator(func(req context.Context) Signal {
+			return g.onDemandSignal(ctx, req)
+		})
+	}
 	if err := g.responder.Run(ctx); err != nil {
 		ch <- err
 	}
 }
 
+func (g *Greenlight) RunAdmin(ctx context.Context, wg *sync.WaitGroup, ch chan error) {
+	defer wg.Done()
+	if err := g.admin.Run(ctx); err != nil {
+		ch <- err
+	}
+}
+
+// TriggerReadiness requests an immediate run of the readiness checks.
+func (g *Greenlight) TriggerReadiness() {
+	if g.cache != nil {
+		g.cache.expire()
+	}
+	select {
+	case g.trigger <- struct{}{}:
+	default: // already triggered
+	}
+}
+
+// SkipStartUpCheck requests to skip the startup check currently failing.
+func (g *Greenlight) SkipStartUpCheck() error {
+	if p, _ := g.state.snapshot(); p != phaseStartUp {
+		return fmt.Errorf("not in %s phase", phaseStartUp)
+	}
+	select {
+	case g.skip <- struct{}{}:
+	default: // already requested
+	}
+	return nil
+}
+
 func (g *Greenlight) RunChildCommand(ctx context.Context, wg *sync.WaitGroup, ch chan error) {
 	defer wg.Done()
 	commands := g.childCmds
@@ -264,7 +497,12 @@ func (g *Greenlight) RunChildCommand(ctx context.Context, wg *sync.WaitGroup, ch
 	}
 	cmd.WaitDelay = 30 * time.Second // TODO: configurable
 
-	err := cmd.Run()
+	err := cmd.Start()
+	if err == nil {
+		g.state.setChildPID(cmd.Process.Pid)
+		err = cmd.Wait()
+		g.state.setChildPID(0)
+	}
 	if err != nil && !ignoreExitError {
 		exitCode := wrapcommander.ResolveExitCode(err)
 		logger.Error("child command failed",
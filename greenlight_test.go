@@ -0,0 +1,248 @@
+package greenlight_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestStaleWatchdog(t *testing.T) {
+	g := newTestGreenlight(t, &greenlight.Config{
+		Responder: &greenlight.ResponderConfig{
+			OnDemand: &greenlight.OnDemandConfig{TTL: 10 * time.Millisecond, Timeout: 100 * time.Millisecond, Periodic: true},
+		},
+		Readiness: &greenlight.PhaseConfig{
+			Interval: 50 * time.Millisecond,
+			Checks: []*greenlight.CheckConfig{
+				{Name: "hung", Timeout: time.Hour, Command: &greenlight.CommandCheckConfig{Run: "sleep 3600"}},
+			},
+			StaleFactor: 2,
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		done := make(chan struct{})
+		go func() { // the readiness checks send a signal after canceled
+			for {
+				select {
+				case <-g.Signals():
+				case <-done:
+					return
+				}
+			}
+		}()
+		wg.Wait()
+		close(done)
+	}()
+	wg.Add(2)
+	go g.RunRedinessChecks(ctx, &wg, make(chan error, 1))
+	go g.RunStaleWatchdog(ctx, &wg)
+
+	select {
+	case s := <-g.Signals():
+		if s != greenlight.SignalRed {
+			t.Errorf("unexpected signal: %s", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the watchdog does not signal red")
+	}
+
+	// on-demand evaluation must not override the red of the watchdog.
+	start := time.Now()
+	if s := g.OnDemandSignal(ctx, context.Background()); s != greenlight.SignalRed {
+		t.Errorf("unexpected on-demand signal: %s", s)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("stale on-demand evaluation must not wait for the hung checks: %s", elapsed)
+	}
+}
+
+func TestOnDemandSignalTimeout(t *testing.T) {
+	g := newTestGreenlight(t, &greenlight.Config{
+		Responder: &greenlight.ResponderConfig{
+			OnDemand: &greenlight.OnDemandConfig{Timeout: 100 * time.Millisecond},
+		},
+		Readiness: &greenlight.PhaseConfig{
+			Checks: []*greenlight.CheckConfig{
+				{Name: "hung", Timeout: time.Hour, Command: &greenlight.CommandCheckConfig{Run: "sleep 3600"}},
+			},
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a request gives up by the on-demand timeout
+	start := time.Now()
+	if s := g.OnDemandSignal(ctx, context.Background()); s == greenlight.SignalGreen {
+		t.Errorf("unexpected signal: %s", s)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("on-demand evaluation must time out: %s", elapsed)
+	}
+
+	// a request gives up by its context
+	req, cancelReq := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelReq()
+	start = time.Now()
+	if s := g.OnDemandSignal(ctx, req); s == greenlight.SignalGreen {
+		t.Errorf("unexpected signal: %s", s)
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("on-demand evaluation must end with the request: %s", elapsed)
+	}
+}
+
+func TestRunChildCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires /proc")
+	}
+	childCheck, err := greenlight.NewProcessChecker(&greenlight.CheckConfig{
+		Name:    "child",
+		Timeout: time.Second,
+		Process: &greenlight.ProcessCheckConfig{Child: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// start runs the child command, and waits for the comm of the child process.
+	start := func(t *testing.T, cmds []string, comm string) (*greenlight.Greenlight, context.CancelFunc, chan error) {
+		t.Helper()
+		g := newTestGreenlight(t, &greenlight.Config{})
+		g.SetChildCommand(cmds)
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		ch := make(chan error, 1)
+		wg.Add(1)
+		go g.RunChildCommand(ctx, &wg, ch)
+		t.Cleanup(func() {
+			cancel()
+			wg.Wait()
+		})
+		for i := 0; comm != ""; i++ {
+			if pid := g.ChildPID(); pid != 0 {
+				b, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+				if strings.TrimSpace(string(b)) == comm {
+					break
+				}
+			}
+			if i > 100 {
+				t.Fatal("child command is not started")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return g, cancel, ch
+	}
+
+	t.Run("exit error", func(t *testing.T) {
+		g, _, ch := start(t, []string{"sh", "-c", "exit 3"}, "")
+		select {
+		case err := <-ch:
+			if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("child command did not exit")
+		}
+		if pid := g.ChildPID(); pid != 0 {
+			t.Errorf("child pid should be reset, got %d", pid)
+		}
+	})
+
+	t.Run("SIGTERM", func(t *testing.T) {
+		g, cancel, ch := start(t, []string{"sleep", "3600"}, "sleep")
+		ctx := g.WithState(context.Background())
+		if err := childCheck.Run(ctx); err != nil {
+			t.Errorf("child process check failed: %s", err)
+		}
+		cancel()
+		select {
+		case err := <-ch:
+			if err == nil || !strings.Contains(err.Error(), "child command exited") {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("child command did not exit by SIGTERM")
+		}
+		if pid := g.ChildPID(); pid != 0 {
+			t.Errorf("child pid should be reset, got %d", pid)
+		}
+		if err := childCheck.Run(ctx); err == nil {
+			t.Error("child process check should fail after exit")
+		}
+	})
+}
+
+func TestReadinessSignals(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires the disk check")
+	}
+	ok := &greenlight.CheckConfig{Name: "ok", Timeout: time.Second, Command: &greenlight.CommandCheckConfig{Run: "true"}}
+	failed := &greenlight.CheckConfig{Name: "failed", Timeout: time.Second, Command: &greenlight.CommandCheckConfig{Run: "false"}}
+	degraded := &greenlight.CheckConfig{Name: "degraded", Timeout: time.Second, Disk: &greenlight.DiskCheckConfig{Path: t.TempDir(), WarnFree: "100%"}}
+	tests := []struct {
+		name         string
+		checks       []*greenlight.CheckConfig
+		expectSignal greenlight.Signal
+		expectCode   int
+		expectBody   string
+	}{
+		{"ok", []*greenlight.CheckConfig{ok}, greenlight.SignalGreen, http.StatusOK, "OK\n"},
+		{"warning", []*greenlight.CheckConfig{ok, degraded}, greenlight.SignalYellow, http.StatusOK, "OK (degraded)\n"},
+		{"failure", []*greenlight.CheckConfig{ok, failed}, greenlight.SignalRed, http.StatusServiceUnavailable, "Service Unavailable\n"},
+		{"warning and failure", []*greenlight.CheckConfig{degraded, failed}, greenlight.SignalRed, http.StatusServiceUnavailable, "Service Unavailable\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGreenlight(t, &greenlight.Config{
+				Responder: &greenlight.ResponderConfig{
+					OnDemand: &greenlight.OnDemandConfig{Periodic: true},
+				},
+				Readiness: &greenlight.PhaseConfig{Interval: time.Hour, Checks: tt.checks},
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+			wg.Add(1)
+			go g.RunRedinessChecks(ctx, &wg, make(chan error, 1))
+
+			select {
+			case s := <-g.Signals():
+				if s != tt.expectSignal {
+					t.Errorf("unexpected signal: %s", s)
+				}
+				g.SetSignal(s)
+			case <-time.After(5 * time.Second):
+				t.Fatal("the readiness checks do not signal")
+			}
+			if s := g.OnDemandSignal(ctx, context.Background()); s != tt.expectSignal {
+				t.Errorf("unexpected on-demand signal: %s", s)
+			}
+
+			w := httptest.NewRecorder()
+			g.ResponderHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.expectCode {
+				t.Errorf("unexpected status: %d", w.Code)
+			}
+			if b := w.Body.String(); b != tt.expectBody {
+				t.Errorf("unexpected body: %q", b)
+			}
+			if h := w.Header().Get("X-Greenlight-Signal"); h != string(tt.expectSignal) {
+				t.Errorf("unexpected X-Greenlight-Signal: %q", h)
+			}
+		})
+	}
+}
@@ -3,29 +3,60 @@ package greenlight
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/net/http2"
 )
 
 var (
-	DefaultHTTPTimeout = 15 * time.Second
+	DefaultHTTPTimeout      = 15 * time.Second
+	DefaultHTTPMaxRedirects = 10
 )
 
 type HTTPCheckConfig struct {
-	URL                string            `yaml:"url"`
-	Method             string            `yaml:"method"`
-	Headers            map[string]string `yaml:"headers"`
-	Body               string            `yaml:"body"`
-	ExpectCode         string            `yaml:"expect_code"`
-	ExpectPattern      string            `yaml:"expect_pattern"`
-	NoCheckCertificate bool              `yaml:"no_check_certificate"`
+	URL                string                `yaml:"url"`
+	Method             string                `yaml:"method"`
+	Headers            map[string]string     `yaml:"headers"`
+	Body               string                `yaml:"body"`
+	ExpectCode         string                `yaml:"expect_code"`
+	ExpectPattern      string                `yaml:"expect_pattern"`
+	NoCheckCertificate bool                  `yaml:"no_check_certificate"`
+	UnixSocket         string                `yaml:"unix_socket"`
+	ExpectJSON         []JSONAssertionConfig `yaml:"expect_json"`
+	ExpectHeaders      map[string]string     `yaml:"expect_headers"`
+	MaxLatency         time.Duration         `yaml:"max_latency"`
+	ClientCert         string                `yaml:"client_cert"`
+	ClientKey          string                `yaml:"client_key"`
+	CAFile             string                `yaml:"ca_file"`
+	ServerName         string                `yaml:"server_name"`
+	Proxy              string                `yaml:"proxy"`
+	FollowRedirects    *bool                 `yaml:"follow_redirects"`
+	MaxRedirects       int                   `yaml:"max_redirects"`
+	HTTP2              bool                  `yaml:"http2"`
+	H2C                bool                  `yaml:"h2c"`
+	BasicAuth          *HTTPBasicAuthConfig  `yaml:"basic_auth"`
+	BearerTokenFile    string                `yaml:"bearer_token_file"`
+	BearerTokenEnv     string                `yaml:"bearer_token_env"`
+}
+
+type HTTPBasicAuthConfig struct {
+	Username     string `yaml:"username"`
+	PasswordFile string `yaml:"password_file"`
+	PasswordEnv  string `yaml:"password_env"`
 }
 
 func NewHTTPChecker(cfg *CheckConfig) (*HTTPChecker, error) {
@@ -35,27 +66,109 @@ func NewHTTPChecker(cfg *CheckConfig) (*HTTPChecker, error) {
 		NoCheckCertificate: cfg.HTTP.NoCheckCertificate,
 		Headers:            cfg.HTTP.Headers,
 		Body:               cfg.HTTP.Body,
+		UnixSocket:         cfg.HTTP.UnixSocket,
+		MaxLatency:         cfg.HTTP.MaxLatency,
+		ClientCert:         cfg.HTTP.ClientCert,
+		ClientKey:          cfg.HTTP.ClientKey,
+		ServerName:         cfg.HTTP.ServerName,
+		FollowRedirects:    true,
+		MaxRedirects:       cfg.HTTP.MaxRedirects,
+		HTTP2:              cfg.HTTP.HTTP2,
+		H2C:                cfg.HTTP.H2C,
+		BasicAuth:          cfg.HTTP.BasicAuth,
+		BearerTokenFile:    cfg.HTTP.BearerTokenFile,
+		BearerTokenEnv:     cfg.HTTP.BearerTokenEnv,
+	}
+	if cfg.HTTP.FollowRedirects != nil {
+		p.FollowRedirects = *cfg.HTTP.FollowRedirects
 	}
 	var err error
-	u, err := url.Parse(cfg.HTTP.URL)
+	rawURL := cfg.HTTP.URL
+	if rest, ok := strings.CutPrefix(rawURL, "http+unix://"); ok {
+		// http+unix://%2Fvar%2Frun%2Fapp.sock/path
+		socket, path, _ := strings.Cut(rest, "/")
+		if p.UnixSocket, err = url.PathUnescape(socket); err != nil {
+			return nil, fmt.Errorf("invalid url %s: %w", cfg.HTTP.URL, err)
+		}
+		rawURL = "http://localhost/" + path
+	}
+	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid url %s: %w", cfg.HTTP.URL, err)
 	}
 	if u.Scheme != "http" && u.Scheme != "https" {
-		return nil, fmt.Errorf("invalid url %s: scheme must be http or https", cfg.HTTP.URL)
+		return nil, fmt.Errorf("invalid url %s: scheme must be http, https, or http+unix", cfg.HTTP.URL)
 	}
 	p.URL = u.String()
 
+	if p.UnixSocket != "" && u.Scheme == "https" && !p.NoCheckCertificate && p.ServerName == "" {
+		// the host of the url is not the peer of a unix socket
+		return nil, errors.New("server_name is required for https over a unix socket")
+	}
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		return nil, errors.New("client_cert and client_key must be specified together")
+	}
+	if cfg.HTTP.CAFile != "" {
+		if p.RootCAs, err = readCertPool(cfg.HTTP.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	if px := cfg.HTTP.Proxy; px != "" && px != "environment" {
+		if p.Proxy, err = url.Parse(px); err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", px, err)
+		}
+		if p.Proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %s: host is required", px)
+		}
+	}
+	p.ProxyFromEnvironment = cfg.HTTP.Proxy == "environment"
+	if p.H2C {
+		if u.Scheme != "http" {
+			return nil, fmt.Errorf("invalid url %s: h2c requires a http url", cfg.HTTP.URL)
+		}
+		if cfg.HTTP.Proxy != "" {
+			return nil, errors.New("h2c does not support proxy")
+		}
+		if p.ClientCert != "" || cfg.HTTP.CAFile != "" || p.ServerName != "" || p.NoCheckCertificate {
+			return nil, errors.New("h2c does not use tls: client_cert, ca_file, server_name, and no_check_certificate are not allowed")
+		}
+	} else if p.HTTP2 && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url %s: http2 requires a https url, use h2c for a http url", cfg.HTTP.URL)
+	}
+	if p.BasicAuth != nil && (p.BearerTokenFile != "" || p.BearerTokenEnv != "") {
+		return nil, errors.New("basic_auth and bearer token are exclusive")
+	}
+
 	if pt := cfg.HTTP.ExpectPattern; pt != "" {
 		p.ExpectPattern, err = regexp.Compile(pt)
 		if err != nil {
 			return nil, fmt.Errorf("invalid expect_pattern %s: %w", pt, err)
 		}
 	}
+	for i, c := range cfg.HTTP.ExpectJSON {
+		a, err := newJSONAssertion(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_json[%d]: %w", i, err)
+		}
+		p.ExpectJSON = append(p.ExpectJSON, a)
+	}
+	if len(cfg.HTTP.ExpectHeaders) > 0 {
+		p.ExpectHeaders = make(map[string]*regexp.Regexp, len(cfg.HTTP.ExpectHeaders))
+		for name, pt := range cfg.HTTP.ExpectHeaders {
+			re, err := regexp.Compile(pt)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expect_headers %s: %w", name, err)
+			}
+			p.ExpectHeaders[name] = re
+		}
+	}
 	// default
 	if p.Method == "" {
 		p.Method = http.MethodGet
 	}
+	if p.MaxRedirects == 0 {
+		p.MaxRedirects = DefaultHTTPMaxRedirects
+	}
 	if cfg.HTTP.ExpectCode == "" {
 		p.ExpectCodeFunc = func(code int) bool {
 			return code >= 200 && code < 400
@@ -71,14 +184,31 @@ func NewHTTPChecker(cfg *CheckConfig) (*HTTPChecker, error) {
 }
 
 type HTTPChecker struct {
-	URL                string
-	Method             string
-	Headers            map[string]string
-	Body               string
-	ExpectCodeFunc     func(code int) bool
-	ExpectPattern      *regexp.Regexp
-	Timeout            time.Duration
-	NoCheckCertificate bool
+	URL                  string
+	Method               string
+	Headers              map[string]string
+	Body                 string
+	ExpectCodeFunc       func(code int) bool
+	ExpectPattern        *regexp.Regexp
+	ExpectJSON           []*jsonAssertion
+	ExpectHeaders        map[string]*regexp.Regexp
+	MaxLatency           time.Duration
+	Timeout              time.Duration
+	NoCheckCertificate   bool
+	UnixSocket           string
+	ClientCert           string
+	ClientKey            string
+	RootCAs              *x509.CertPool // nil means the system roots
+	ServerName           string
+	Proxy                *url.URL
+	ProxyFromEnvironment bool
+	FollowRedirects      bool
+	MaxRedirects         int
+	HTTP2                bool
+	H2C                  bool
+	BasicAuth            *HTTPBasicAuthConfig
+	BearerTokenFile      string
+	BearerTokenEnv       string
 
 	name string
 }
@@ -93,6 +223,13 @@ func (p *HTTPChecker) Run(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
 	defer cancel()
 
+	timings := &httpTimings{}
+	defer func() {
+		if t, ok := timings.done(); ok { // not ok when the request was not sent
+			setCheckDetail(ctx, "timings", t)
+		}
+	}()
+	ctx = httptrace.WithClientTrace(ctx, timings.trace())
 	req, err := http.NewRequestWithContext(ctx, p.Method, p.URL, strings.NewReader(p.Body))
 	if err != nil {
 		return err
@@ -100,28 +237,140 @@ func (p *HTTPChecker) Run(ctx context.Context) error {
 	for name, value := range p.Headers {
 		req.Header.Set(name, value)
 	}
-	req.Header.Set("Connection", "close") // do not keep alive to health check.
+	if !p.H2C {
+		req.Header.Set("Connection", "close") // do not keep alive to health check.
+	}
 	req.Header.Set("User-Agent", "greenlight/"+Version)
+	if err := p.setAuth(req); err != nil {
+		return err
+	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: p.NoCheckCertificate},
+	client, err := p.newClient()
+	if err != nil {
+		return err
 	}
-	client := &http.Client{Transport: tr}
+	defer client.CloseIdleConnections() // HTTP/2 connections are not closed by the Connection header
 
 	logger.Debug(fmt.Sprintf("http request %s %s", req.Method, req.URL))
+	timings.begin()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("http request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if p.HTTP2 && resp.ProtoMajor != 2 {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("expect HTTP/2, but got %s", resp.Proto)
+	}
+	if err := p.checkResponse(resp); err != nil {
+		return err
+	}
+	t, _ := timings.done()
+	logger.Debug("http response", "status", resp.StatusCode, "timings", t.String())
+
+	if p.MaxLatency > 0 && t.total > p.MaxLatency {
+		return newWarning("http response took %s, exceeds max_latency %s (%s)", t.total, p.MaxLatency, t)
+	}
+	return nil
+}
+
+func (p *HTTPChecker) newClient() (*http.Client, error) {
+	dialer := &net.Dialer{Timeout: p.Timeout}
+	dial := dialer.DialContext
+	if p.UnixSocket != "" {
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", p.UnixSocket)
+		}
+	}
+
+	var tr http.RoundTripper
+	if p.H2C {
+		tr = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+	} else {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: p.NoCheckCertificate,
+			RootCAs:            p.RootCAs,
+			ServerName:         p.ServerName,
+		}
+		if p.ClientCert != "" {
+			// read every time to follow the rotation of the certificate
+			cert, err := tls.LoadX509KeyPair(p.ClientCert, p.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("load client certificate failed: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		t := &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			DialContext:       dial,
+			ForceAttemptHTTP2: p.HTTP2,
+		}
+		if p.Proxy != nil {
+			t.Proxy = http.ProxyURL(p.Proxy)
+		} else if p.ProxyFromEnvironment {
+			t.Proxy = http.ProxyFromEnvironment
+		}
+		tr = t
+	}
+
+	client := &http.Client{Transport: tr}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !p.FollowRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > p.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", p.MaxRedirects)
+		}
+		return nil
+	}
+	return client, nil
+}
 
+// setAuth sets the Authorization header. Secrets are read every time to follow the rotation.
+func (p *HTTPChecker) setAuth(req *http.Request) error {
+	if p.BasicAuth != nil {
+		password, err := loadSecret(p.BasicAuth.PasswordFile, p.BasicAuth.PasswordEnv)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(p.BasicAuth.Username, password)
+	}
+	if p.BearerTokenFile != "" || p.BearerTokenEnv != "" {
+		token, err := loadSecret(p.BearerTokenFile, p.BearerTokenEnv)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return errors.New("bearer token is empty")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// checkResponse checks the response code, headers, and body.
+func (p *HTTPChecker) checkResponse(resp *http.Response) error {
 	if p.ExpectCodeFunc != nil {
 		if !p.ExpectCodeFunc(resp.StatusCode) {
 			return fmt.Errorf("expect code not match: %d", resp.StatusCode)
 		}
 	}
+	for name, re := range p.ExpectHeaders {
+		values := resp.Header.Values(name)
+		if len(values) == 0 {
+			return fmt.Errorf("expect header not found: %s", name)
+		}
+		if !slices.ContainsFunc(values, re.MatchString) {
+			return fmt.Errorf("expect header not match: %s: %s", name, re.String())
+		}
+	}
 
-	if p.ExpectPattern == nil {
+	if p.ExpectPattern == nil && len(p.ExpectJSON) == 0 {
 		io.Copy(io.Discard, resp.Body)
 		return nil
 	}
@@ -134,9 +383,100 @@ func (p *HTTPChecker) Run(ctx context.Context) error {
 			return fmt.Errorf("expect pattern not match: %s", p.ExpectPattern.String())
 		}
 	}
+	if len(p.ExpectJSON) > 0 {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("expect json: invalid response body: %w", err)
+		}
+		for _, a := range p.ExpectJSON {
+			if err := a.assert(doc); err != nil {
+				return fmt.Errorf("expect json not match: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
+// httpTimings records the timings of a http request by httptrace.
+// The hooks are called from the transport goroutines, even after client.Do returns
+// (e.g. a dial in flight on timeout, or the racing dials of happy eyeballs), so mu guards all the fields.
+type httpTimings struct {
+	mu        sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	httpTiming
+}
+
+// httpTiming is a snapshot of httpTimings.
+// Each duration is zero when the phase did not happen, e.g. a reused connection or a plain http.
+type httpTiming struct {
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	firstByte time.Duration
+	total     time.Duration
+}
+
+// record calls f with the lock held.
+func (t *httpTimings) record(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f()
+}
+
+func (t *httpTimings) begin() {
+	t.record(func() { t.start = time.Now() })
+}
+
+// done returns a snapshot of the timings. The total is fixed at the first call.
+// ok is false when the request was not sent.
+func (t *httpTimings) done() (httpTiming, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		return httpTiming{}, false
+	}
+	if t.total == 0 {
+		t.total = time.Since(t.start)
+	}
+	return t.httpTiming, true
+}
+
+func (t *httpTimings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.record(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.record(func() { t.dns = time.Since(t.dnsStart) }) },
+		ConnectStart: func(_, _ string) {
+			t.record(func() {
+				if t.connStart.IsZero() {
+					t.connStart = time.Now()
+				}
+			})
+		},
+		ConnectDone:          func(_, _ string, _ error) { t.record(func() { t.connect = time.Since(t.connStart) }) },
+		TLSHandshakeStart:    func() { t.record(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.record(func() { t.tls = time.Since(t.tlsStart) }) },
+		GotFirstResponseByte: func() { t.record(func() { t.firstByte = time.Since(t.start) }) },
+	}
+}
+
+// MarshalJSON encodes the timings in the check event.
+func (t httpTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		DNS       string `json:"dns"`
+		Connect   string `json:"connect"`
+		TLS       string `json:"tls"`
+		FirstByte string `json:"ttfb"`
+		Total     string `json:"total"`
+	}{t.dns.String(), t.connect.String(), t.tls.String(), t.firstByte.String(), t.total.String()})
+}
+
+func (t httpTiming) String() string {
+	return fmt.Sprintf("dns=%s connect=%s tls=%s ttfb=%s total=%s", t.dns, t.connect, t.tls, t.firstByte, t.total)
+}
+
 // newExpectCodeFunc parses a string of comma separated HTTP status codes and
 // returns a function that checks if the given code is in the list.
 // e.g. "200,201,202-204,300-399"
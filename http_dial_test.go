@@ -0,0 +1,84 @@
+//go:build linux || darwin || freebsd
+
+package greenlight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// newHungListener returns an address that accepts no more connections.
+// The accept queue is full, so a dial to it hangs until the timeout.
+func newHungListener(t *testing.T) string {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(sa.(*syscall.SockaddrInet4).Port))
+	for {
+		// fills the accept queue, which is never accepted
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return addr
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+}
+
+// the dial is still in flight when the request times out, run with -race.
+func TestCheckEventTimingsDialTimeout(t *testing.T) {
+	addr := newHungListener(t)
+	g := newTestGreenlight(t, &greenlight.Config{
+		Readiness: &greenlight.PhaseConfig{
+			Checks: []*greenlight.CheckConfig{
+				{Name: "hung", Timeout: 200 * time.Millisecond, HTTP: &greenlight.HTTPCheckConfig{URL: "http://" + addr}},
+			},
+		},
+	})
+	ch := g.SubscribeEvents()
+	if err := g.CheckRediness(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	var ev greenlight.CheckEvent
+	select {
+	case e := <-ch:
+		ev = e.Data.(greenlight.CheckEvent)
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Details struct {
+			Timings map[string]string `json:"timings"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if total, err := time.ParseDuration(decoded.Details.Timings["total"]); err != nil || total < 200*time.Millisecond {
+		t.Errorf("invalid total of timings: %s", b)
+	}
+	time.Sleep(100 * time.Millisecond) // lets the dial end
+}
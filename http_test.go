@@ -1,9 +1,24 @@
 package greenlight_test
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/fujiwara/greenlight"
+	"github.com/goccy/go-yaml"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func TestNewExpectCodeFunc(t *testing.T) {
@@ -70,3 +85,360 @@ func TestNewExpectCodeFunc(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPCheckerExpectJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			fmt.Fprint(w, "OK")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"UP","components":{"db":{"status":"UP","details":{"pool.active":3}},"diskSpace":{"status":"UP"}},"items":[{"name":"a"},{"name":"b"}],"ready":true}`)
+	}))
+	defer ts.Close()
+
+	f := false
+	tests := []struct {
+		name       string
+		path       string
+		assertions []greenlight.JSONAssertionConfig
+		expectErr  bool
+	}{
+		{"equals", "/", []greenlight.JSONAssertionConfig{{Path: "$.status", Equals: "UP"}, {Path: "$.components.db.status", Equals: "UP"}}, false},
+		{"equals not match", "/", []greenlight.JSONAssertionConfig{{Path: "$.status", Equals: "DOWN"}}, true},
+		{"equals bool", "/", []greenlight.JSONAssertionConfig{{Path: "ready", Equals: true}}, false},
+		{"equals number", "/", []greenlight.JSONAssertionConfig{{Path: `$.components.db.details["pool.active"]`, Equals: uint64(3)}}, false},
+		{"value", "/", []greenlight.JSONAssertionConfig{{Path: `$.components.db.details["pool.active"]`, Value: "< 10"}}, false},
+		{"value exceeded", "/", []greenlight.JSONAssertionConfig{{Path: `$.components.db.details["pool.active"]`, Value: "< 3"}}, true},
+		{"value not a number", "/", []greenlight.JSONAssertionConfig{{Path: "$.status", Value: "> 0"}}, true},
+		{"exists", "/", []greenlight.JSONAssertionConfig{{Path: "$.components.diskSpace"}}, false},
+		{"does not exist", "/", []greenlight.JSONAssertionConfig{{Path: "$.components.redis"}}, true},
+		{"not exists", "/", []greenlight.JSONAssertionConfig{{Path: "$.error", Exists: &f}}, false},
+		{"index", "/", []greenlight.JSONAssertionConfig{{Path: "$.items[0].name", Equals: "a"}, {Path: "$.items[-1].name", Equals: "b"}}, false},
+		{"index out of range", "/", []greenlight.JSONAssertionConfig{{Path: "$.items[2].name"}}, true},
+		{"length", "/", []greenlight.JSONAssertionConfig{{Path: "$.items", Length: ">= 2"}}, false},
+		{"length not match", "/", []greenlight.JSONAssertionConfig{{Path: "$.items", Length: "0"}}, true},
+		{"not json", "/text", []greenlight.JSONAssertionConfig{{Path: "$.status"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				HTTP: &greenlight.HTTPCheckConfig{
+					URL:        ts.URL + tt.path,
+					ExpectJSON: tt.assertions,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerExpectJSONEqualsYAML(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"UP","db":{"status":"UP","pool":{"active":3,"max":10}},"replicas":[1,2],"lastError":null}`)
+	}))
+	defer ts.Close()
+
+	// the values of equals are decoded from YAML as in a config file.
+	tests := []struct {
+		name      string
+		yaml      string
+		expectErr bool
+	}{
+		{"integer", "path: $.db.pool.active\nequals: 3", false},
+		{"float", "path: $.db.pool.active\nequals: 3.0", false},
+		{"nested object", "path: $.db\nequals: {status: UP, pool: {active: 3, max: 10}}", false},
+		{"nested object not match", "path: $.db\nequals: {status: UP, pool: {active: 3}}", true},
+		{"array", "path: $.replicas\nequals: [1, 2]", false},
+		{"array not match", "path: $.replicas\nequals: [2, 1]", true},
+		{"null", "path: $.lastError\nequals: null", false},
+		{"null not match", "path: $.status\nequals: null", true},
+		{"null does not exist", "path: $.error\nequals: null", true},
+		{"string not null", "path: $.lastError\nequals: \"null\"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a greenlight.JSONAssertionConfig
+			if err := yaml.Unmarshal([]byte(tt.yaml), &a); err != nil {
+				t.Fatal(err)
+			}
+			checker, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				HTTP: &greenlight.HTTPCheckConfig{
+					URL:        ts.URL,
+					ExpectJSON: []greenlight.JSONAssertionConfig{a},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerInvalidExpectJSON(t *testing.T) {
+	for _, a := range []greenlight.JSONAssertionConfig{
+		{Path: "$.items[0"},
+		{Path: "$.items[x]"},
+		{Path: "$.count", Value: "about 3"},
+	} {
+		_, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+			Name: "invalid",
+			HTTP: &greenlight.HTTPCheckConfig{
+				URL:        "http://localhost/",
+				ExpectJSON: []greenlight.JSONAssertionConfig{a},
+			},
+		})
+		if err == nil {
+			t.Errorf("expected error for %#v", a)
+		}
+	}
+}
+
+func TestHTTPCheckerExpectHeadersAndLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Add("Cache-Control", "no-cache")
+		w.Header().Add("Cache-Control", "no-store")
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"status":"UP"}`)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name          string
+		path          string
+		expectHeaders map[string]string
+		maxLatency    time.Duration
+		expectErr     bool
+		expectWarn    bool
+	}{
+		{"headers", "/", map[string]string{"Content-Type": "^application/json", "cache-control": "no-store"}, 0, false, false},
+		{"header not match", "/", map[string]string{"Content-Type": "^text/html"}, 0, true, false},
+		{"header not found", "/", map[string]string{"X-Request-Id": "."}, 0, true, false},
+		{"fast", "/", nil, time.Second, false, false},
+		{"slow", "/slow", nil, 100 * time.Millisecond, true, true},
+		{"slow and header not match", "/slow", map[string]string{"Content-Type": "^text/html"}, 100 * time.Millisecond, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				HTTP: &greenlight.HTTPCheckConfig{
+					URL:           ts.URL + tt.path,
+					ExpectHeaders: tt.expectHeaders,
+					MaxLatency:    tt.maxLatency,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			var w *greenlight.WarningError
+			if errors.As(err, &w) != tt.expectWarn {
+				t.Errorf("unexpected warning: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerClientOptions(t *testing.T) {
+	caPEM, certPEM, keyPEM := newTestCertificate(t, 60)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	tokenFile := filepath.Join(dir, "token")
+	for name, b := range map[string][]byte{caFile: caPEM, certFile: certPEM, keyFile: keyPEM, tokenFile: []byte("s3cret\n")} {
+		if err := os.WriteFile(name, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HTTP_PASSWORD", "secret")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/redirect?n=%d", n-1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	})
+	mux.HandleFunc("/basic", func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "app" || p != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	mux.HandleFunc("/bearer", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	// https with a private CA and a client certificate required
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPEM)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsServer := httptest.NewUnstartedServer(mux)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	h2cServer := httptest.NewServer(h2c.NewHandler(mux, &http2.Server{}))
+	defer h2cServer.Close()
+
+	// a forward proxy that answers by itself
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != "app.internal" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer proxy.Close()
+
+	mTLS := func(c greenlight.HTTPCheckConfig) greenlight.HTTPCheckConfig {
+		c.CAFile, c.ServerName, c.ClientCert, c.ClientKey = caFile, "app.example.com", certFile, keyFile
+		return c
+	}
+	f := false
+	tests := []struct {
+		name         string
+		cfg          greenlight.HTTPCheckConfig
+		expectNewErr bool
+		expectErr    bool
+	}{
+		{"mtls", mTLS(greenlight.HTTPCheckConfig{URL: tlsServer.URL + "/proto"}), false, false},
+		{"mtls http2", mTLS(greenlight.HTTPCheckConfig{URL: tlsServer.URL + "/proto", HTTP2: true, ExpectPattern: "^HTTP/2"}), false, false},
+		{"no client certificate", greenlight.HTTPCheckConfig{URL: tlsServer.URL + "/proto", CAFile: caFile, ServerName: "app.example.com"}, false, true},
+		{"unknown CA", greenlight.HTTPCheckConfig{URL: tlsServer.URL + "/proto", ServerName: "app.example.com", ClientCert: certFile, ClientKey: keyFile}, false, true},
+		{"server name not match", greenlight.HTTPCheckConfig{URL: tlsServer.URL + "/proto", CAFile: caFile, ServerName: "db.example.com", ClientCert: certFile, ClientKey: keyFile}, false, true},
+		{"client_key missing", greenlight.HTTPCheckConfig{URL: tlsServer.URL, ClientCert: certFile}, true, false},
+		{"http2 not supported", greenlight.HTTPCheckConfig{URL: server.URL + "/proto", HTTP2: true}, true, false},
+		{"h2c", greenlight.HTTPCheckConfig{URL: h2cServer.URL + "/proto", H2C: true, ExpectPattern: "^HTTP/2"}, false, false},
+		{"h2c not supported", greenlight.HTTPCheckConfig{URL: server.URL + "/proto", H2C: true}, false, true},
+		{"h2c with client certificate", mTLS(greenlight.HTTPCheckConfig{URL: h2cServer.URL + "/proto", H2C: true}), true, false},
+		{"h2c with no_check_certificate", greenlight.HTTPCheckConfig{URL: h2cServer.URL + "/proto", H2C: true, NoCheckCertificate: true}, true, false},
+		{"h2c with proxy", greenlight.HTTPCheckConfig{URL: h2cServer.URL + "/proto", H2C: true, Proxy: proxy.URL}, true, false},
+		{"proxy", greenlight.HTTPCheckConfig{URL: "http://app.internal/", Proxy: proxy.URL, ExpectCode: "200"}, false, false},
+		{"redirects", greenlight.HTTPCheckConfig{URL: server.URL + "/redirect?n=3", ExpectCode: "200", ExpectPattern: "OK"}, false, false},
+		{"too many redirects", greenlight.HTTPCheckConfig{URL: server.URL + "/redirect?n=3", MaxRedirects: 2}, false, true},
+		{"no follow redirects", greenlight.HTTPCheckConfig{URL: server.URL + "/redirect?n=3", FollowRedirects: &f, ExpectCode: "302"}, false, false},
+		{"basic auth", greenlight.HTTPCheckConfig{URL: server.URL + "/basic", BasicAuth: &greenlight.HTTPBasicAuthConfig{Username: "app", PasswordEnv: "HTTP_PASSWORD"}}, false, false},
+		{"basic auth failed", greenlight.HTTPCheckConfig{URL: server.URL + "/basic", BasicAuth: &greenlight.HTTPBasicAuthConfig{Username: "app"}}, false, true},
+		{"bearer token", greenlight.HTTPCheckConfig{URL: server.URL + "/bearer", BearerTokenFile: tokenFile}, false, false},
+		{"bearer token empty", greenlight.HTTPCheckConfig{URL: server.URL + "/bearer", BearerTokenEnv: "HTTP_EMPTY_TOKEN"}, false, true},
+		{"basic auth and bearer", greenlight.HTTPCheckConfig{URL: server.URL, BasicAuth: &greenlight.HTTPBasicAuthConfig{Username: "app"}, BearerTokenFile: tokenFile}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				HTTP:    &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerUnixSocket(t *testing.T) {
+	caPEM, certPEM, keyPEM := newTestCertificate(t, 60)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host+r.URL.Path)
+	})
+
+	l, path := unixListen(t)
+	server := &httptest.Server{Listener: l, Config: &http.Server{Handler: handler}}
+	server.Start()
+	defer server.Close()
+	tl, tlsPath := unixListen(t)
+	tlsServer := &httptest.Server{Listener: tl, Config: &http.Server{Handler: handler}}
+	tlsServer.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	tests := []struct {
+		name          string
+		cfg           greenlight.HTTPCheckConfig
+		expectNewErr  bool
+		expectErr     bool
+		expectPattern string
+	}{
+		{"unix_socket", greenlight.HTTPCheckConfig{URL: "http://localhost/health", UnixSocket: path}, false, false, "^localhost/health$"},
+		{"http+unix", greenlight.HTTPCheckConfig{URL: "http+unix://" + url.PathEscape(path) + "/health"}, false, false, "^localhost/health$"},
+		{"https server_name", greenlight.HTTPCheckConfig{URL: "https://localhost/health", UnixSocket: tlsPath, ServerName: "app.example.com", CAFile: caFile}, false, false, "^localhost/health$"},
+		{"https server_name mismatch", greenlight.HTTPCheckConfig{URL: "https://localhost/health", UnixSocket: tlsPath, ServerName: "other.example.com", CAFile: caFile}, false, true, ""},
+		{"https without server_name", greenlight.HTTPCheckConfig{URL: "https://app.example.com/health", UnixSocket: tlsPath, CAFile: caFile}, true, false, ""},
+		{"https no_check_certificate", greenlight.HTTPCheckConfig{URL: "https://localhost/health", UnixSocket: tlsPath, NoCheckCertificate: true}, false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ExpectPattern = tt.expectPattern
+			checker, err := greenlight.NewHTTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				HTTP:    &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("expected new error: %v, got: %v", tt.expectNewErr, err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package greenlight
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type JSONAssertionConfig struct {
+	Path   string `yaml:"path"`
+	Equals any    `yaml:"equals"`
+	Value  string `yaml:"value"`
+	Exists *bool  `yaml:"exists"`
+	Length string `yaml:"length"`
+}
+
+// UnmarshalYAML keeps `equals: null` as a JSON null, which is distinguished from no equals.
+func (c *JSONAssertionConfig) UnmarshalYAML(b []byte) error {
+	type plain JSONAssertionConfig
+	if err := yaml.Unmarshal(b, (*plain)(c)); err != nil {
+		return err
+	}
+	var keys map[string]any
+	if err := yaml.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	if v, ok := keys["equals"]; ok && v == nil {
+		c.Equals = json.RawMessage("null")
+	}
+	return nil
+}
+
+// jsonAssertion asserts a value in a JSON document selected by a JSONPath-like selector.
+type jsonAssertion struct {
+	path       string
+	steps      []any // string for a key, int for an index
+	equals     any   // normalized as decoded from JSON
+	hasEquals  bool
+	value      func(float64) bool
+	valueExpr  string
+	exists     *bool
+	length     func(float64) bool
+	lengthExpr string
+}
+
+func newJSONAssertion(cfg JSONAssertionConfig) (*jsonAssertion, error) {
+	steps, err := parseJSONPath(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", cfg.Path, err)
+	}
+	a := &jsonAssertion{
+		path:       cfg.Path,
+		steps:      steps,
+		valueExpr:  cfg.Value,
+		exists:     cfg.Exists,
+		lengthExpr: cfg.Length,
+	}
+	if cfg.Equals != nil {
+		// a round trip through JSON makes the value comparable with the decoded document,
+		// e.g. integers in YAML to float64, and nested maps and arrays.
+		b, err := json.Marshal(cfg.Equals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid equals for %s: %w", cfg.Path, err)
+		}
+		if err := json.Unmarshal(b, &a.equals); err != nil {
+			return nil, fmt.Errorf("invalid equals for %s: %w", cfg.Path, err)
+		}
+		a.hasEquals = true
+	}
+	if cfg.Value != "" {
+		if a.value, err = newCompareFunc(cfg.Value); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", cfg.Path, err)
+		}
+	}
+	if cfg.Length != "" {
+		if a.length, err = newCompareFunc(cfg.Length); err != nil {
+			return nil, fmt.Errorf("invalid length for %s: %w", cfg.Path, err)
+		}
+	}
+	if !a.hasEquals && a.value == nil && a.exists == nil && a.length == nil {
+		exists := true // the path must exist at least
+		a.exists = &exists
+	}
+	return a, nil
+}
+
+func (a *jsonAssertion) assert(doc any) error {
+	v, ok := lookupJSONPath(doc, a.steps)
+	switch {
+	case ok && a.exists != nil && !*a.exists:
+		return fmt.Errorf("%s exists", a.path)
+	case !ok && a.exists != nil && !*a.exists:
+		return nil
+	case !ok:
+		return fmt.Errorf("%s does not exist", a.path)
+	}
+	if a.hasEquals && !reflect.DeepEqual(v, a.equals) {
+		return fmt.Errorf("%s is %s, expected %s", a.path, formatJSON(v), formatJSON(a.equals))
+	}
+	if a.value != nil {
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s is %s, not a number", a.path, formatJSON(v))
+		}
+		if !a.value(n) {
+			return fmt.Errorf("%s is %g, does not satisfy %s", a.path, n, a.valueExpr)
+		}
+	}
+	if a.length != nil {
+		var n int
+		switch vv := v.(type) {
+		case []any:
+			n = len(vv)
+		case map[string]any:
+			n = len(vv)
+		case string:
+			n = len(vv)
+		default:
+			return fmt.Errorf("%s is %s, has no length", a.path, formatJSON(v))
+		}
+		if !a.length(float64(n)) {
+			return fmt.Errorf("%s length is %d, does not satisfy %s", a.path, n, a.lengthExpr)
+		}
+	}
+	return nil
+}
+
+// parseJSONPath parses a selector like `$.components.db.status`, `$.items[0].name`, or `$["key.with.dots"]`.
+// The leading `$` and `.` are optional.
+func parseJSONPath(s string) ([]any, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(s), "$")
+	var steps []any
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, errors.New("unterminated [")
+			}
+			inner := strings.TrimSpace(p[i+1 : i+end])
+			if q, err := strconv.Unquote(inner); err == nil {
+				steps = append(steps, q)
+			} else if n, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, n)
+			} else {
+				return nil, fmt.Errorf("invalid [%s]", inner)
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(p[i:], ".[")
+			if end < 0 {
+				end = len(p) - i
+			}
+			steps = append(steps, p[i:i+end])
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+func lookupJSONPath(doc any, steps []any) (any, bool) {
+	v := doc
+	for _, step := range steps {
+		switch s := step.(type) {
+		case string:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			a, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			if s < 0 {
+				s += len(a) // -1 is the last element
+			}
+			if s < 0 || s >= len(a) {
+				return nil, false
+			}
+			v = a[s]
+		}
+	}
+	return v, true
+}
+
+func formatJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,463 @@
+package greenlight
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultMetricsMaxBytes     = int64(10 * 1024 * 1024)
+	DefaultMetricsRateInterval = 1 * time.Second
+)
+
+type MetricsCheckConfig struct {
+	URL                string                   `yaml:"url"`
+	Headers            map[string]string        `yaml:"headers"`
+	NoCheckCertificate bool                     `yaml:"no_check_certificate"`
+	RateInterval       time.Duration            `yaml:"rate_interval"`
+	Conditions         []MetricsConditionConfig `yaml:"conditions"`
+}
+
+type MetricsConditionConfig struct {
+	Series    string `yaml:"series"`
+	Aggregate string `yaml:"aggregate"`
+	Rate      bool   `yaml:"rate"`
+	Expect    string `yaml:"expect"`
+}
+
+type MetricsChecker struct {
+	URL                string
+	Headers            map[string]string
+	NoCheckCertificate bool
+	RateInterval       time.Duration
+	Conditions         []*metricsCondition
+	Timeout            time.Duration
+
+	name string
+}
+
+type metricsCondition struct {
+	expr      string
+	selector  *seriesSelector
+	aggregate string
+	rate      bool
+	expect    func(float64) bool
+}
+
+func (p *MetricsChecker) Name() string {
+	return p.name
+}
+
+func NewMetricsChecker(cfg *CheckConfig) (*MetricsChecker, error) {
+	c := cfg.Metrics
+	p := &MetricsChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		URL:                c.URL,
+		Headers:            c.Headers,
+		NoCheckCertificate: c.NoCheckCertificate,
+		RateInterval:       c.RateInterval,
+	}
+	if p.URL == "" {
+		return nil, errors.New("metrics url is required")
+	}
+	if len(c.Conditions) == 0 {
+		return nil, errors.New("metrics conditions are required")
+	}
+	for i, cc := range c.Conditions {
+		sel, err := parseSeriesSelector(cc.Series)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions[%d] series %s: %w", i, cc.Series, err)
+		}
+		expect, err := newCompareFunc(cc.Expect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions[%d] expect: %w", i, err)
+		}
+		agg := cc.Aggregate
+		switch agg {
+		case "":
+			agg = "sum"
+		case "sum", "min", "max", "avg", "count":
+		default:
+			return nil, fmt.Errorf("invalid conditions[%d] aggregate %s: must be sum, min, max, avg, or count", i, agg)
+		}
+		expr := cc.Series
+		if cc.Rate {
+			expr = "rate(" + expr + ")"
+		}
+		p.Conditions = append(p.Conditions, &metricsCondition{
+			expr:      fmt.Sprintf("%s(%s) %s", agg, expr, strings.TrimSpace(cc.Expect)),
+			selector:  sel,
+			aggregate: agg,
+			rate:      cc.Rate,
+			expect:    expect,
+		})
+	}
+	// default
+	if p.RateInterval == 0 {
+		p.RateInterval = DefaultMetricsRateInterval
+	}
+	return p, nil
+}
+
+func (p *MetricsChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "metricschecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: p.NoCheckCertificate},
+		},
+	}
+	first, err := p.scrape(ctx, client)
+	if err != nil {
+		return err
+	}
+	logger.Debug(fmt.Sprintf("scraped %d samples from %s", len(first.samples), p.URL))
+
+	// a rate needs the second scrape.
+	var second *metricsScrape
+	if slices.ContainsFunc(p.Conditions, func(c *metricsCondition) bool { return c.rate }) {
+		select {
+		case <-time.After(min(p.RateInterval, p.Timeout/2)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if second, err = p.scrape(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range p.Conditions {
+		var values []float64
+		if c.rate {
+			values = rateValues(first, second, c.selector)
+		} else {
+			for _, s := range first.samples {
+				if c.selector.match(s) {
+					values = append(values, s.value)
+				}
+			}
+		}
+		if len(values) == 0 && c.aggregate != "count" {
+			return fmt.Errorf("metrics no series matched %s", c.selector.raw)
+		}
+		v := aggregateValues(c.aggregate, values)
+		logger.Debug(fmt.Sprintf("%s: %g (%d series)", c.expr, v, len(values)))
+		if !c.expect(v) {
+			return fmt.Errorf("metrics condition %s is not satisfied: %g", c.expr, v)
+		}
+	}
+	return nil
+}
+
+type metricsScrape struct {
+	at      time.Time
+	samples []*metricSample
+}
+
+func (p *MetricsChecker) scrape(ctx context.Context, client *http.Client) (*metricsScrape, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	req.Header.Set("User-Agent", "greenlight/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("metrics request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics unexpected status %s", resp.Status)
+	}
+	at := time.Now()
+	// reads one more byte to tell a truncated body from a body just at the limit.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMetricsMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("metrics read failed: %w", err)
+	}
+	if int64(len(b)) > DefaultMetricsMaxBytes {
+		return nil, fmt.Errorf("metrics response exceeds max bytes %d", DefaultMetricsMaxBytes)
+	}
+	samples, err := parseMetrics(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("metrics parse failed: %w", err)
+	}
+	return &metricsScrape{at: at, samples: samples}, nil
+}
+
+// rateValues returns per-second rates of the matched series between two scrapes.
+func rateValues(first, second *metricsScrape, sel *seriesSelector) []float64 {
+	prev := make(map[string]float64)
+	for _, s := range first.samples {
+		if sel.match(s) {
+			prev[s.key()] = s.value
+		}
+	}
+	dt := second.at.Sub(first.at).Seconds()
+	var values []float64
+	for _, s := range second.samples {
+		if !sel.match(s) {
+			continue
+		}
+		v0, ok := prev[s.key()]
+		if !ok {
+			continue
+		}
+		delta := s.value - v0
+		if delta < 0 { // counter reset
+			delta = s.value
+		}
+		values = append(values, delta/dt)
+	}
+	return values
+}
+
+func aggregateValues(agg string, values []float64) float64 {
+	switch agg {
+	case "count":
+		return float64(len(values))
+	case "min":
+		v := math.Inf(1)
+		for _, x := range values {
+			v = math.Min(v, x)
+		}
+		return v
+	case "max":
+		v := math.Inf(-1)
+		for _, x := range values {
+			v = math.Max(v, x)
+		}
+		return v
+	}
+	var sum float64
+	for _, x := range values {
+		sum += x
+	}
+	if agg == "avg" {
+		return sum / float64(len(values))
+	}
+	return sum
+}
+
+type metricSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// key identifies the series.
+func (s *metricSample) key() string {
+	keys := make([]string, 0, len(s.labels))
+	for k := range s.labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	b.WriteString(s.name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ",%s=%q", k, s.labels[k])
+	}
+	return b.String()
+}
+
+// parseMetrics parses the Prometheus text exposition format.
+func parseMetrics(r io.Reader) ([]*metricSample, error) {
+	var samples []*metricSample
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseMetricLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, s.Err()
+}
+
+// parseMetricLine parses `name{label="value",...} value [timestamp]`.
+func parseMetricLine(line string) (*metricSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return nil, fmt.Errorf("invalid sample %q", line)
+	}
+	sample := &metricSample{name: line[:end], labels: map[string]string{}}
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labels %q: %w", line, err)
+		}
+		for _, l := range labels {
+			sample.labels[l.name] = l.value
+		}
+		rest = rest[n:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no value %q", line)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", line)
+	}
+	sample.value = v
+	return sample, nil
+}
+
+type labelMatcher struct {
+	name  string
+	op    string // =, !=, =~, !~
+	value string
+	re    *regexp.Regexp
+}
+
+// parseLabels parses `{name="value",...}` and returns the matchers and the consumed length.
+// Operators other than "=" are allowed for selectors.
+func parseLabels(s string) ([]*labelMatcher, int, error) {
+	var matchers []*labelMatcher
+	i := 1 // skip "{"
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, errors.New("unterminated labels")
+		}
+		if s[i] == '}' {
+			return matchers, i + 1, nil
+		}
+		start := i
+		for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+			i++
+		}
+		name := s[start:i]
+		if name == "" {
+			return nil, 0, errors.New("empty label name")
+		}
+		var op string
+		for _, o := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, 0, fmt.Errorf("no operator after %s", name)
+		}
+		i += len(op)
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %s value must be quoted", name)
+		}
+		var value strings.Builder
+		for i++; ; i++ {
+			if i >= len(s) {
+				return nil, 0, errors.New("unterminated label value")
+			}
+			c := s[i]
+			if c == '"' {
+				i++
+				break
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					c = '\n'
+				default:
+					c = s[i]
+				}
+			}
+			value.WriteByte(c)
+		}
+		matchers = append(matchers, &labelMatcher{name: name, op: op, value: value.String()})
+	}
+}
+
+// seriesSelector is a PromQL-like series selector. e.g. `http_requests_total{code=~"5..",method!="GET"}`
+type seriesSelector struct {
+	raw      string
+	name     string
+	matchers []*labelMatcher
+}
+
+func parseSeriesSelector(s string) (*seriesSelector, error) {
+	s = strings.TrimSpace(s)
+	sel := &seriesSelector{raw: s}
+	name, rest, _ := strings.Cut(s, "{")
+	sel.name = strings.TrimSpace(name)
+	if sel.name == "" {
+		return nil, errors.New("metric name is required")
+	}
+	if rest == "" {
+		return sel, nil
+	}
+	matchers, n, err := parseLabels("{" + rest)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest[n-1:]) != "" {
+		return nil, fmt.Errorf("unexpected %q after labels", rest[n-1:])
+	}
+	for _, m := range matchers {
+		if m.op == "=~" || m.op == "!~" {
+			re, err := regexp.Compile("^(?:" + m.value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp for %s: %w", m.name, err)
+			}
+			m.re = re
+		}
+	}
+	sel.matchers = matchers
+	return sel, nil
+}
+
+func (sel *seriesSelector) match(s *metricSample) bool {
+	if s.name != sel.name {
+		return false
+	}
+	for _, m := range sel.matchers {
+		v := s.labels[m.name] // a missing label is an empty string
+		switch m.op {
+		case "=":
+			if v != m.value {
+				return false
+			}
+		case "!=":
+			if v == m.value {
+				return false
+			}
+		case "=~":
+			if !m.re.MatchString(v) {
+				return false
+			}
+		case "!~":
+			if m.re.MatchString(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
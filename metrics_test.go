@@ -0,0 +1,106 @@
+package greenlight_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestMetricsChecker(t *testing.T) {
+	var scrapes atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := scrapes.Add(1)
+		fmt.Fprint(w, `# HELP http_requests_in_flight Current number of requests.
+# TYPE http_requests_in_flight gauge
+http_requests_in_flight{handler="/api",method="GET"} 120
+http_requests_in_flight{handler="/api",method="POST"} 30
+http_requests_in_flight{handler="/static",method="GET"} 400
+# TYPE jvm_gc_pause_seconds_sum counter
+`)
+		// the counter increases 0.5 per scrape
+		fmt.Fprintf(w, "jvm_gc_pause_seconds_sum{gc=\"G1 Young \\\"Generation\\\"\"} %g 1700000000000\n", float64(n)*0.5)
+		fmt.Fprint(w, "up NaN\n")
+	}))
+	defer ts.Close()
+
+	cond := func(series, aggregate, expect string) greenlight.MetricsConditionConfig {
+		return greenlight.MetricsConditionConfig{Series: series, Aggregate: aggregate, Expect: expect}
+	}
+	tests := []struct {
+		name       string
+		conditions []greenlight.MetricsConditionConfig
+		expectErr  bool
+	}{
+		{"sum", []greenlight.MetricsConditionConfig{cond("http_requests_in_flight", "", "< 600")}, false},
+		{"sum exceeded", []greenlight.MetricsConditionConfig{cond("http_requests_in_flight", "", "< 500")}, true},
+		{"label", []greenlight.MetricsConditionConfig{cond(`http_requests_in_flight{handler="/api"}`, "", "== 150")}, false},
+		{"label regexp", []greenlight.MetricsConditionConfig{cond(`http_requests_in_flight{handler=~"/st.*"}`, "max", "400")}, false},
+		{"label not equal", []greenlight.MetricsConditionConfig{cond(`http_requests_in_flight{method!="GET"}`, "", "30")}, false},
+		{"count", []greenlight.MetricsConditionConfig{cond(`http_requests_in_flight{method="GET"}`, "count", "2")}, false},
+		{"avg", []greenlight.MetricsConditionConfig{cond("http_requests_in_flight", "avg", ">= 183")}, false},
+		{"no series", []greenlight.MetricsConditionConfig{cond("missing_metric", "", "0")}, true},
+		{"escaped label", []greenlight.MetricsConditionConfig{cond(`jvm_gc_pause_seconds_sum{gc="G1 Young \"Generation\""}`, "count", "1")}, false},
+		{"NaN", []greenlight.MetricsConditionConfig{cond("up", "", "1")}, true},
+		{"rate", []greenlight.MetricsConditionConfig{{Series: "jvm_gc_pause_seconds_sum", Rate: true, Expect: "> 0"}}, false},
+		{"rate exceeded", []greenlight.MetricsConditionConfig{{Series: "jvm_gc_pause_seconds_sum", Rate: true, Expect: "< 0.1"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewMetricsChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				Metrics: &greenlight.MetricsCheckConfig{
+					URL:          ts.URL,
+					RateInterval: 100 * time.Millisecond,
+					Conditions:   tt.conditions,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMetricsCheckerMaxBytes(t *testing.T) {
+	body := "up 1\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	orig := greenlight.DefaultMetricsMaxBytes
+	defer func() { greenlight.DefaultMetricsMaxBytes = orig }()
+	checker, err := greenlight.NewMetricsChecker(&greenlight.CheckConfig{
+		Name:    "max bytes",
+		Timeout: time.Second,
+		Metrics: &greenlight.MetricsCheckConfig{
+			URL:        ts.URL,
+			Conditions: []greenlight.MetricsConditionConfig{{Series: "up", Expect: "1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	greenlight.DefaultMetricsMaxBytes = int64(len(body))
+	if err := checker.Run(context.Background()); err != nil {
+		t.Errorf("body at the limit should pass: %s", err)
+	}
+	// the truncated body "up 1" would parse, the check must fail instead
+	greenlight.DefaultMetricsMaxBytes = int64(len(body)) - 1
+	if err := checker.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "exceeds max bytes") {
+		t.Errorf("expected max bytes error, got: %v", err)
+	}
+}
@@ -0,0 +1,542 @@
+package greenlight
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultMySQLPort = "3306"
+)
+
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+
+	mysqlMaxPacketSize  = 1<<24 - 1
+	mysqlCharsetUTF8MB4 = 45
+	mysqlComQuit        = 0x01
+	mysqlComQuery       = 0x03
+)
+
+type MySQLCheckConfig struct {
+	Host                    string   `yaml:"host"`
+	Port                    string   `yaml:"port"`
+	User                    string   `yaml:"user"`
+	PasswordFile            string   `yaml:"password_file"`
+	PasswordEnv             string   `yaml:"password_env"`
+	Database                string   `yaml:"database"`
+	TLS                     bool     `yaml:"tls"`
+	NoCheckCertificate      bool     `yaml:"no_check_certificate"`
+	AllowPublicKeyRetrieval bool     `yaml:"allow_public_key_retrieval"`
+	Query                   string   `yaml:"query"`
+	Column                  string   `yaml:"column"`
+	Expect                  string   `yaml:"expect"`
+	ExpectPattern           string   `yaml:"expect_pattern"`
+	MaxValue                *float64 `yaml:"max_value"`
+}
+
+type MySQLChecker struct {
+	Host                    string
+	Port                    string
+	User                    string
+	PasswordFile            string
+	PasswordEnv             string
+	Database                string
+	TLS                     bool
+	NoCheckCertificate      bool
+	AllowPublicKeyRetrieval bool
+	Query                   string
+	Column                  string
+	Expect                  *string
+	ExpectPattern           *regexp.Regexp
+	MaxValue                *float64
+	Timeout                 time.Duration
+
+	name string
+}
+
+func (p *MySQLChecker) Name() string {
+	return p.name
+}
+
+func NewMySQLChecker(cfg *CheckConfig) (*MySQLChecker, error) {
+	p := &MySQLChecker{
+		name:                    cfg.Name,
+		Timeout:                 cfg.Timeout,
+		Host:                    cfg.MySQL.Host,
+		Port:                    cfg.MySQL.Port,
+		User:                    cfg.MySQL.User,
+		PasswordFile:            cfg.MySQL.PasswordFile,
+		PasswordEnv:             cfg.MySQL.PasswordEnv,
+		Database:                cfg.MySQL.Database,
+		TLS:                     cfg.MySQL.TLS,
+		NoCheckCertificate:      cfg.MySQL.NoCheckCertificate,
+		AllowPublicKeyRetrieval: cfg.MySQL.AllowPublicKeyRetrieval,
+		Query:                   cfg.MySQL.Query,
+		Column:                  cfg.MySQL.Column,
+		MaxValue:                cfg.MySQL.MaxValue,
+	}
+	if p.User == "" {
+		return nil, errors.New("mysql user is required")
+	}
+	if isUnixAddr(p.Host) && p.TLS {
+		return nil, errors.New("mysql tls is not supported over a unix socket")
+	}
+	if cfg.MySQL.Expect != "" {
+		p.Expect = &cfg.MySQL.Expect
+	}
+	if cfg.MySQL.ExpectPattern != "" {
+		pt, err := regexp.Compile(cfg.MySQL.ExpectPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+		p.ExpectPattern = pt
+	}
+	if p.Query == "" && (p.Expect != nil || p.ExpectPattern != nil || p.MaxValue != nil) {
+		return nil, errors.New("mysql query is required for expect, expect_pattern, or max_value")
+	}
+	// default
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+	if p.Port == "" {
+		p.Port = DefaultMySQLPort
+	}
+	return p, nil
+}
+
+func (p *MySQLChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "mysqlchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	password, err := loadSecret(p.PasswordFile, p.PasswordEnv)
+	if err != nil {
+		return err
+	}
+
+	addr := dialAddr(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, false, false, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("mysql connect failed: %w", err)
+	}
+	mc := &mysqlConn{conn: conn}
+	defer func() { mc.conn.Close() }()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+	logger.Debug("connected " + addr)
+
+	version, err := mc.handshake(ctx, p, password)
+	if err != nil {
+		return fmt.Errorf("mysql handshake failed: %w", err)
+	}
+	logger.Debug("authenticated", "server_version", version)
+	defer mc.writePacket([]byte{mysqlComQuit})
+
+	if p.Query == "" {
+		return nil
+	}
+	value, null, err := mc.query(p.Query, p.Column)
+	if err != nil {
+		return fmt.Errorf("mysql query failed: %w", err)
+	}
+	logger.Debug("query result", "value", value, "null", null)
+	if p.Expect != nil && (null || value != *p.Expect) {
+		return fmt.Errorf("mysql unexpected result: %s", mysqlValueString(value, null))
+	}
+	if p.ExpectPattern != nil && (null || !p.ExpectPattern.MatchString(value)) {
+		return fmt.Errorf("mysql result %s does not match expect_pattern %s", mysqlValueString(value, null), p.ExpectPattern.String())
+	}
+	if p.MaxValue != nil {
+		v, err := strconv.ParseFloat(value, 64)
+		if null || err != nil {
+			return fmt.Errorf("mysql result %s is not a number", mysqlValueString(value, null))
+		}
+		if v > *p.MaxValue {
+			return fmt.Errorf("mysql result %s exceeds max_value %g", value, *p.MaxValue)
+		}
+	}
+	return nil
+}
+
+func mysqlValueString(value string, null bool) string {
+	if null {
+		return "NULL"
+	}
+	return value
+}
+
+// mysqlConn is a minimal MySQL client.
+type mysqlConn struct {
+	conn net.Conn
+	seq  byte
+}
+
+func (c *mysqlConn) writePacket(payload []byte) error {
+	h := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), c.seq}
+	c.seq++
+	_, err := c.conn.Write(append(h, payload...))
+	return err
+}
+
+func (c *mysqlConn) readPacket() ([]byte, error) {
+	var h [4]byte
+	if _, err := io.ReadFull(c.conn, h[:]); err != nil {
+		return nil, err
+	}
+	n := int(h[0]) | int(h[1])<<8 | int(h[2])<<16
+	c.seq = h[3] + 1
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return nil, err
+	}
+	if n > 0 && payload[0] == 0xff {
+		return nil, mysqlError(payload)
+	}
+	return payload, nil
+}
+
+// handshake reads the initial handshake, and authenticates.
+func (c *mysqlConn) handshake(ctx context.Context, p *MySQLChecker, password string) (string, error) {
+	greeting, err := c.readPacket()
+	if err != nil {
+		return "", err
+	}
+	if len(greeting) < 1 {
+		return "", errors.New("empty handshake")
+	}
+	if greeting[0] != 10 {
+		return "", fmt.Errorf("unsupported protocol version %d", greeting[0])
+	}
+	version, rest, ok := bytes.Cut(greeting[1:], []byte{0})
+	if !ok || len(rest) < 4+8+1+2 {
+		return "", errors.New("invalid handshake")
+	}
+	rest = rest[4:] // connection id
+	scramble := bytes.Clone(rest[:8])
+	rest = rest[9:]
+	serverCaps := uint32(binary.LittleEndian.Uint16(rest))
+	rest = rest[2:]
+	plugin := "mysql_native_password"
+	if len(rest) >= 16 {
+		serverCaps |= uint32(binary.LittleEndian.Uint16(rest[3:])) << 16
+		authLen := int(rest[5])
+		rest = rest[16:]
+		if n := max(13, authLen-8); len(rest) >= n {
+			scramble = append(scramble, bytes.TrimRight(rest[:n], "\x00")...)
+			rest = rest[n:]
+			if name, _, ok := bytes.Cut(rest, []byte{0}); ok && len(name) > 0 {
+				plugin = string(name)
+			}
+		}
+	}
+	if serverCaps&mysqlClientProtocol41 == 0 {
+		return "", errors.New("server does not support protocol 4.1")
+	}
+
+	caps := uint32(mysqlClientLongPassword | mysqlClientProtocol41 | mysqlClientTransactions |
+		mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if p.Database != "" {
+		caps |= mysqlClientConnectWithDB
+	}
+	header := binary.LittleEndian.AppendUint32(nil, 0)
+	header = binary.LittleEndian.AppendUint32(header, mysqlMaxPacketSize)
+	header = append(header, mysqlCharsetUTF8MB4)
+	header = append(header, make([]byte, 23)...)
+
+	// a unix socket is a secure transport as the MySQL clients treat it.
+	secure := isUnixAddr(p.Host)
+	if p.TLS {
+		if serverCaps&mysqlClientSSL == 0 {
+			return "", errors.New("server does not support TLS")
+		}
+		caps |= mysqlClientSSL
+		binary.LittleEndian.PutUint32(header, caps)
+		if err := c.writePacket(header); err != nil { // SSLRequest
+			return "", err
+		}
+		tc := tls.Client(c.conn, &tls.Config{
+			ServerName:         p.Host,
+			InsecureSkipVerify: p.NoCheckCertificate,
+		})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return "", fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		c.conn = tc
+		secure = true
+	}
+	binary.LittleEndian.PutUint32(header, caps)
+
+	authResp, err := mysqlAuthResponse(plugin, password, scramble)
+	if err != nil {
+		return "", err
+	}
+	resp := append(header, p.User...)
+	resp = append(resp, 0, byte(len(authResp)))
+	resp = append(resp, authResp...)
+	if p.Database != "" {
+		resp = append(resp, p.Database...)
+		resp = append(resp, 0)
+	}
+	resp = append(resp, plugin...)
+	resp = append(resp, 0)
+	if err := c.writePacket(resp); err != nil {
+		return "", err
+	}
+	return string(version), c.authResult(plugin, password, scramble, secure, p.AllowPublicKeyRetrieval)
+}
+
+func (c *mysqlConn) authResult(plugin, password string, scramble []byte, secure, allowPublicKey bool) error {
+	for {
+		pkt, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(pkt) == 0 {
+			return errors.New("empty auth response")
+		}
+		switch pkt[0] {
+		case 0x00: // OK
+			return nil
+		case 0xfe: // AuthSwitchRequest
+			name, data, _ := bytes.Cut(pkt[1:], []byte{0})
+			plugin, scramble = string(name), bytes.TrimRight(data, "\x00")
+			authResp, err := mysqlAuthResponse(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(authResp); err != nil {
+				return err
+			}
+		case 0x01: // AuthMoreData
+			if plugin != "caching_sha2_password" || len(pkt) < 2 {
+				return fmt.Errorf("unexpected auth data for %s", plugin)
+			}
+			switch {
+			case pkt[1] == 3: // fast auth succeeded. OK follows.
+			case pkt[1] == 4 && secure: // full auth over TLS
+				if err := c.writePacket(append([]byte(password), 0)); err != nil {
+					return err
+				}
+			case pkt[1] == 4 && !allowPublicKey:
+				// the public key from the server can't be verified, so a MITM can give its own key.
+				return errors.New("caching_sha2_password full authentication requires tls or allow_public_key_retrieval")
+			case pkt[1] == 4:
+				if err := c.writePacket([]byte{2}); err != nil { // request public key
+					return err
+				}
+			default: // public key
+				enc, err := mysqlEncryptPassword(pkt[1:], password, scramble)
+				if err != nil {
+					return err
+				}
+				if err := c.writePacket(enc); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected auth response 0x%02x", pkt[0])
+		}
+	}
+}
+
+// query runs the query, and returns the value of the column in the first row.
+// The first column is used if column is empty.
+func (c *mysqlConn) query(q, column string) (string, bool, error) {
+	c.seq = 0
+	if err := c.writePacket(append([]byte{mysqlComQuery}, q...)); err != nil {
+		return "", false, err
+	}
+	pkt, err := c.readPacket()
+	if err != nil {
+		return "", false, err
+	}
+	if len(pkt) == 0 {
+		return "", false, errors.New("empty query response")
+	}
+	if pkt[0] == 0x00 {
+		return "", false, errors.New("no result set")
+	}
+	ncols, _, ok := mysqlLenEncInt(pkt)
+	if !ok || ncols == 0 {
+		return "", false, errors.New("invalid column count")
+	}
+	index := -1
+	for i := 0; i < int(ncols); i++ {
+		def, err := c.readPacket()
+		if err != nil {
+			return "", false, err
+		}
+		// catalog, schema, table, org_table, name
+		var name []byte
+		for j := 0; j < 5; j++ {
+			if name, def, ok = mysqlLenEncString(def); !ok {
+				return "", false, errors.New("invalid column definition")
+			}
+		}
+		if index < 0 && (column == "" || strings.EqualFold(column, string(name))) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return "", false, fmt.Errorf("column %s not found", column)
+	}
+	if _, err := c.readPacket(); err != nil { // EOF
+		return "", false, err
+	}
+
+	var value string
+	var null bool
+	rows := 0
+	for {
+		row, err := c.readPacket()
+		if err != nil {
+			return "", false, err
+		}
+		if len(row) == 0 {
+			return "", false, errors.New("empty row")
+		}
+		if row[0] == 0xfe && len(row) < 9 { // EOF
+			break
+		}
+		if rows == 0 {
+			for i := 0; i <= index; i++ {
+				if len(row) > 0 && row[0] == 0xfb {
+					null, row = i == index, row[1:]
+					continue
+				}
+				var v []byte
+				if v, row, ok = mysqlLenEncString(row); !ok {
+					return "", false, errors.New("truncated row")
+				}
+				if i == index {
+					value = string(v)
+				}
+			}
+		}
+		rows++
+	}
+	if rows == 0 {
+		return "", false, errors.New("no rows")
+	}
+	return value, null, nil
+}
+
+func mysqlLenEncInt(b []byte) (uint64, []byte, bool) {
+	if len(b) == 0 {
+		return 0, b, false
+	}
+	switch b[0] {
+	case 0xfc:
+		if len(b) < 3 {
+			return 0, b, false
+		}
+		return uint64(binary.LittleEndian.Uint16(b[1:])), b[3:], true
+	case 0xfd:
+		if len(b) < 4 {
+			return 0, b, false
+		}
+		return uint64(b[1]) | uint64(b[2])<<8 | uint64(b[3])<<16, b[4:], true
+	case 0xfe:
+		if len(b) < 9 {
+			return 0, b, false
+		}
+		return binary.LittleEndian.Uint64(b[1:]), b[9:], true
+	default:
+		return uint64(b[0]), b[1:], true
+	}
+}
+
+func mysqlLenEncString(b []byte) ([]byte, []byte, bool) {
+	n, rest, ok := mysqlLenEncInt(b)
+	if !ok || uint64(len(rest)) < n {
+		return nil, b, false
+	}
+	return rest[:n], rest[n:], true
+}
+
+func mysqlError(pkt []byte) error {
+	if len(pkt) < 3 {
+		return errors.New("unknown error")
+	}
+	code := binary.LittleEndian.Uint16(pkt[1:])
+	msg := pkt[3:]
+	state := ""
+	if len(msg) >= 6 && msg[0] == '#' {
+		state, msg = string(msg[1:6]), msg[6:]
+	}
+	return fmt.Errorf("ERROR %d (%s): %s", code, state, msg)
+}
+
+func mysqlAuthResponse(plugin, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		h1 := sha1.Sum([]byte(password))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(bytes.Clone(scramble), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	case "caching_sha2_password":
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	default:
+		return nil, fmt.Errorf("unsupported auth plugin %s", plugin)
+	}
+}
+
+// mysqlEncryptPassword encrypts the password by the server's RSA public key for caching_sha2_password.
+func mysqlEncryptPassword(pemKey []byte, password string, scramble []byte) ([]byte, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("invalid server public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server public key: %w", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("server public key is not RSA")
+	}
+	if len(scramble) == 0 {
+		return nil, errors.New("empty scramble")
+	}
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaPub, plain, nil)
+}
@@ -0,0 +1,312 @@
+package greenlight_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// fakeMySQL is an in-process server speaking a subset of the MySQL protocol.
+type fakeMySQL struct {
+	user     string
+	password string
+	plugin   string   // auth plugin in the initial handshake
+	switchTo string   // sends AuthSwitchRequest to the plugin if not empty
+	fullAuth bool     // requires caching_sha2_password full authentication
+	greeting []byte   // replaces the initial handshake if not nil
+	result   [][]byte // packets replied to COM_QUERY
+}
+
+func (s *fakeMySQL) run(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := s.serve(conn); err != nil && err != io.EOF {
+					t.Log("fake mysql:", err)
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func mysqlSend(w io.Writer, seq byte, payload []byte) error {
+	h := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+	_, err := w.Write(append(h, payload...))
+	return err
+}
+
+func mysqlRecv(r io.Reader) (byte, []byte, error) {
+	var h [4]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, int(h[0])|int(h[1])<<8|int(h[2])<<16)
+	_, err := io.ReadFull(r, payload)
+	return h[3], payload, err
+}
+
+func mysqlErrPacket(code uint16, state, msg string) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0xff}, code)
+	return append(b, "#"+state+msg...)
+}
+
+func mysqlLenEnc(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func mysqlColumnDef(name string) []byte {
+	var b []byte
+	for _, s := range []string{"def", "", "", "", name, name} {
+		b = append(b, mysqlLenEnc(s)...)
+	}
+	return append(b, 0x0c, 0x2d, 0x00, 0, 0, 0, 0, 0xfd, 0, 0, 0, 0, 0)
+}
+
+var mysqlEOF = []byte{0xfe, 0, 0, 0x02, 0}
+
+// mysqlResultSet returns the packets of a result set with the columns and a row.
+// nil in row is NULL.
+func mysqlResultSet(columns []string, row []*string) [][]byte {
+	pkts := [][]byte{{byte(len(columns))}}
+	for _, c := range columns {
+		pkts = append(pkts, mysqlColumnDef(c))
+	}
+	pkts = append(pkts, mysqlEOF)
+	var r []byte
+	for _, v := range row {
+		if v == nil {
+			r = append(r, 0xfb)
+		} else {
+			r = append(r, mysqlLenEnc(*v)...)
+		}
+	}
+	return append(pkts, r, mysqlEOF)
+}
+
+func mysqlScramble(plugin, password string, scramble []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	switch plugin {
+	case "mysql_native_password":
+		h1 := sha1.Sum([]byte(password))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(bytes.Clone(scramble), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:]
+	default:
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:]
+	}
+}
+
+// fullAuthenticate performs caching_sha2_password full authentication.
+// The password is sent in clear text on a secure transport, or encrypted by the public key on request.
+func (s *fakeMySQL) fullAuthenticate(conn net.Conn, seq byte, scramble []byte) (byte, error) {
+	if err := mysqlSend(conn, seq+1, []byte{0x01, 0x04}); err != nil {
+		return 0, err
+	}
+	seq, p, err := mysqlRecv(conn)
+	if err != nil {
+		return 0, err
+	}
+	password := p
+	if bytes.Equal(p, []byte{2}) { // public key request
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return 0, err
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return 0, err
+		}
+		pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		if err := mysqlSend(conn, seq+1, append([]byte{0x01}, pemKey...)); err != nil {
+			return 0, err
+		}
+		var enc []byte
+		if seq, enc, err = mysqlRecv(conn); err != nil {
+			return 0, err
+		}
+		if password, err = rsa.DecryptOAEP(sha1.New(), nil, key, enc, nil); err != nil {
+			return 0, err
+		}
+		for i := range password {
+			password[i] ^= scramble[i%len(scramble)]
+		}
+	}
+	if string(password) != s.password+"\x00" {
+		mysqlSend(conn, seq+1, mysqlErrPacket(1045, "28000", "Access denied"))
+		return 0, fmt.Errorf("access denied")
+	}
+	return seq, nil
+}
+
+func (s *fakeMySQL) serve(conn net.Conn) error {
+	scramble := []byte("0123456789abcdefghij")
+	greeting := s.greeting
+	if greeting == nil {
+		greeting = append([]byte{10}, "8.0.0\x00"...)
+		greeting = append(greeting, 1, 0, 0, 0)
+		greeting = append(greeting, scramble[:8]...)
+		greeting = append(greeting, 0, 0x00, 0x82, 45, 0x02, 0x00, 0x08, 0x00, 21)
+		greeting = append(greeting, make([]byte, 10)...)
+		greeting = append(greeting, scramble[8:]...)
+		greeting = append(greeting, 0)
+		greeting = append(greeting, s.plugin+"\x00"...)
+	}
+	if err := mysqlSend(conn, 0, greeting); err != nil {
+		return err
+	}
+	seq, resp, err := mysqlRecv(conn)
+	if err != nil {
+		return err
+	}
+	if len(resp) < 32 {
+		return fmt.Errorf("short handshake response")
+	}
+	user, rest, _ := bytes.Cut(resp[32:], []byte{0})
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return fmt.Errorf("short handshake response")
+	}
+	auth := rest[1 : 1+rest[0]]
+	if string(user) != s.user {
+		return mysqlSend(conn, seq+1, mysqlErrPacket(1045, "28000", "Access denied"))
+	}
+	plugin := s.plugin
+	if s.switchTo != "" {
+		plugin, scramble = s.switchTo, []byte("jihgfedcba9876543210")
+		if err := mysqlSend(conn, seq+1, append(append([]byte{0xfe}, plugin+"\x00"...), append(scramble, 0)...)); err != nil {
+			return err
+		}
+		if seq, auth, err = mysqlRecv(conn); err != nil {
+			return err
+		}
+	}
+	// the scramble is not verified for full authentication, as the password is not cached.
+	if !s.fullAuth && !bytes.Equal(auth, mysqlScramble(plugin, s.password, scramble)) {
+		return mysqlSend(conn, seq+1, mysqlErrPacket(1045, "28000", "Access denied"))
+	}
+	if plugin == "caching_sha2_password" && s.fullAuth {
+		if seq, err = s.fullAuthenticate(conn, seq, scramble); err != nil {
+			return err
+		}
+	} else if plugin == "caching_sha2_password" {
+		seq++
+		if err := mysqlSend(conn, seq, []byte{0x01, 0x03}); err != nil { // fast auth
+			return err
+		}
+	}
+	if err := mysqlSend(conn, seq+1, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}); err != nil {
+		return err
+	}
+
+	for {
+		_, p, err := mysqlRecv(conn)
+		if err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return fmt.Errorf("empty command")
+		}
+		switch p[0] {
+		case 0x01: // COM_QUIT
+			return nil
+		case 0x03: // COM_QUERY
+			for i, pkt := range s.result {
+				if err := mysqlSend(conn, byte(i+1), pkt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func TestMySQLChecker(t *testing.T) {
+	t.Setenv("MYSQL_PWD", "secret")
+	one, value, maxValue := "1", "2", 1.0
+	tests := []struct {
+		name      string
+		server    fakeMySQL
+		cfg       greenlight.MySQLCheckConfig
+		expectErr bool
+	}{
+		{"native password", fakeMySQL{user: "app", password: "secret", plugin: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, false},
+		{"native wrong password", fakeMySQL{user: "app", password: "other", plugin: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, true},
+		{"caching sha2 fast auth", fakeMySQL{user: "app", password: "secret", plugin: "caching_sha2_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, false},
+		{"caching sha2 full auth without tls", fakeMySQL{user: "app", password: "secret", plugin: "caching_sha2_password", fullAuth: true}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, true},
+		{"caching sha2 full auth by public key", fakeMySQL{user: "app", password: "secret", plugin: "caching_sha2_password", fullAuth: true}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD", AllowPublicKeyRetrieval: true}, false},
+		{"caching sha2 full auth wrong password", fakeMySQL{user: "app", password: "other", plugin: "caching_sha2_password", fullAuth: true}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD", AllowPublicKeyRetrieval: true}, true},
+		{"empty password", fakeMySQL{user: "app", plugin: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "app"}, false},
+		{"unknown user", fakeMySQL{user: "app", plugin: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "nobody"}, true},
+		{"auth switch", fakeMySQL{user: "app", password: "secret", plugin: "caching_sha2_password", switchTo: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, false},
+		{"auth switch wrong password", fakeMySQL{user: "app", password: "other", plugin: "caching_sha2_password", switchTo: "mysql_native_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, true},
+		{"auth switch unsupported plugin", fakeMySQL{user: "app", password: "secret", plugin: "mysql_native_password", switchTo: "sha256_password"}, greenlight.MySQLCheckConfig{User: "app", PasswordEnv: "MYSQL_PWD"}, true},
+		{"query", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"a", "b"}, []*string{&one, &value})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1 AS a, 2 AS b", Column: "b", Expect: "2"}, false},
+		{"query unexpected", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"a"}, []*string{&one})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1 AS a", Expect: "2"}, true},
+		{"query null", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"a", "b"}, []*string{nil, &value})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT NULL AS a, 2 AS b", Column: "b", Expect: "2"}, false},
+		{"query null expected", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"a"}, []*string{nil})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT NULL AS a", ExpectPattern: ".*"}, true},
+		{"query max_value", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"lag"}, []*string{&value})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 2 AS lag", MaxValue: &maxValue}, true},
+		{"column not found", fakeMySQL{user: "app", plugin: "mysql_native_password", result: mysqlResultSet([]string{"a"}, []*string{&one})}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1 AS a", Column: "x"}, true},
+		{"ERR packet", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{mysqlErrPacket(1064, "42000", "syntax error")}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELEC 1"}, true},
+		{"short ERR packet", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{0xff}}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+		{"empty greeting", fakeMySQL{greeting: []byte{}}, greenlight.MySQLCheckConfig{User: "app"}, true},
+		{"short greeting", fakeMySQL{greeting: []byte{10, '8', 0, 1, 0, 0, 0}}, greenlight.MySQLCheckConfig{User: "app"}, true},
+		{"empty query response", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{}}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+		{"short column count", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{0xfc, 1}}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+		{"short column definition", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{1}, {3, 'd', 'e'}}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+		{"empty row", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{1}, mysqlColumnDef("a"), mysqlEOF, {}}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+		{"truncated row", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{2}, mysqlColumnDef("a"), mysqlColumnDef("b"), mysqlEOF, {1, '1'}, mysqlEOF}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1, 2", Column: "b"}, true},
+		{"no rows", fakeMySQL{user: "app", plugin: "mysql_native_password", result: [][]byte{{1}, mysqlColumnDef("a"), mysqlEOF, mysqlEOF}}, greenlight.MySQLCheckConfig{User: "app", Query: "SELECT 1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _ := net.SplitHostPort(tt.server.run(t))
+			tt.cfg.Host, tt.cfg.Port = host, port
+			checker, err := greenlight.NewMySQLChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				MySQL:   &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package greenlight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultOnDemandTTL     = 1 * time.Second
+	DefaultOnDemandTimeout = 10 * time.Second
+)
+
+type OnDemandConfig struct {
+	TTL      time.Duration `yaml:"ttl"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Periodic bool          `yaml:"periodic"`
+}
+
+// resultCache caches a result of the readiness checks for ttl.
+// Concurrent callers share one evaluation.
+type resultCache struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	at   time.Time
+	err  error
+	call *inflightCall
+}
+
+type inflightCall struct {
+	done chan struct{}
+	err  error
+}
+
+func newResultCache(ttl time.Duration) *resultCache {
+	if ttl == 0 {
+		ttl = DefaultOnDemandTTL
+	}
+	return &resultCache{ttl: ttl}
+}
+
+// do returns the cached result, or waits for an evaluation by f until ctx is done.
+// f runs in its own goroutine, so a caller giving up does not cancel the evaluation shared with others.
+func (c *resultCache) do(ctx context.Context, f func() error) error {
+	c.mu.Lock()
+	if !c.at.IsZero() && time.Since(c.at) < c.ttl {
+		defer c.mu.Unlock()
+		return c.err
+	}
+	call := c.call
+	if call == nil {
+		call = &inflightCall{done: make(chan struct{})}
+		c.call = call
+		go func() {
+			call.err = f()
+			c.mu.Lock()
+			c.at, c.err, c.call = time.Now(), call.err, nil
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return fmt.Errorf("readiness checks not completed: %w", ctx.Err())
+	}
+}
+
+func (c *resultCache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at = time.Time{}
+}
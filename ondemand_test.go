@@ -0,0 +1,71 @@
+package greenlight_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestResultCache(t *testing.T) {
+	var calls atomic.Int32
+	errCheck := errors.New("check failed")
+	do := greenlight.NewResultCacheFunc(time.Second, func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return errCheck
+	})
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := do(ctx); !errors.Is(err, errCheck) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("concurrent calls must share one evaluation: %d", n)
+	}
+
+	// cached
+	if err := do(ctx); !errors.Is(err, errCheck) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("result must be cached: %d", n)
+	}
+}
+
+func TestResultCacheWaitCanceled(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	do := greenlight.NewResultCacheFunc(time.Second, func(ctx context.Context) error {
+		calls.Add(1)
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := do(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting caller must give up by its context: %v", err)
+	}
+
+	// the evaluation continues, and its result is shared
+	close(release)
+	if err := do(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("a canceled caller must not cancel the evaluation: %d", n)
+	}
+}
@@ -0,0 +1,534 @@
+package greenlight
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultPostgresPort = "5432"
+)
+
+const (
+	pgProtocolVersion = 196608 // 3.0
+	pgSSLRequestCode  = 80877103
+)
+
+type PostgresCheckConfig struct {
+	Host               string `yaml:"host"`
+	Port               string `yaml:"port"`
+	User               string `yaml:"user"`
+	PasswordFile       string `yaml:"password_file"`
+	PasswordEnv        string `yaml:"password_env"`
+	Database           string `yaml:"database"`
+	SSLMode            string `yaml:"sslmode"`
+	CAFile             string `yaml:"ca_file"`
+	NoCheckCertificate bool   `yaml:"no_check_certificate"`
+	Query              string `yaml:"query"`
+	Expect             string `yaml:"expect"`
+	ExpectPattern      string `yaml:"expect_pattern"`
+}
+
+type PostgresChecker struct {
+	Host               string
+	Port               string
+	User               string
+	PasswordFile       string
+	PasswordEnv        string
+	Database           string
+	SSLMode            string
+	RootCAs            *x509.CertPool // nil means the system roots
+	NoCheckCertificate bool
+	Query              string
+	Expect             *string
+	ExpectPattern      *regexp.Regexp
+	Timeout            time.Duration
+
+	name string
+}
+
+func (p *PostgresChecker) Name() string {
+	return p.name
+}
+
+func NewPostgresChecker(cfg *CheckConfig) (*PostgresChecker, error) {
+	p := &PostgresChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		Host:               cfg.Postgres.Host,
+		Port:               cfg.Postgres.Port,
+		User:               cfg.Postgres.User,
+		PasswordFile:       cfg.Postgres.PasswordFile,
+		PasswordEnv:        cfg.Postgres.PasswordEnv,
+		Database:           cfg.Postgres.Database,
+		SSLMode:            cfg.Postgres.SSLMode,
+		NoCheckCertificate: cfg.Postgres.NoCheckCertificate,
+		Query:              cfg.Postgres.Query,
+	}
+	if p.User == "" {
+		return nil, errors.New("postgres user is required")
+	}
+	switch p.SSLMode {
+	case "":
+		p.SSLMode = "prefer"
+	case "disable", "prefer", "require":
+	case "verify-ca", "verify-full":
+		if p.NoCheckCertificate {
+			return nil, fmt.Errorf("no_check_certificate conflicts with sslmode %s", p.SSLMode)
+		}
+	default:
+		return nil, fmt.Errorf("invalid sslmode %s: must be disable, prefer, require, verify-ca, or verify-full", p.SSLMode)
+	}
+	if isUnixAddr(p.Host) && p.SSLMode != "disable" && p.SSLMode != "prefer" {
+		return nil, fmt.Errorf("sslmode %s is not supported over a unix socket", p.SSLMode)
+	}
+	if cfg.Postgres.CAFile != "" {
+		var err error
+		if p.RootCAs, err = readCertPool(cfg.Postgres.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Postgres.Expect != "" {
+		p.Expect = &cfg.Postgres.Expect
+	}
+	if cfg.Postgres.ExpectPattern != "" {
+		pt, err := regexp.Compile(cfg.Postgres.ExpectPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+		p.ExpectPattern = pt
+	}
+	if p.Query == "" && (p.Expect != nil || p.ExpectPattern != nil) {
+		return nil, errors.New("postgres query is required for expect or expect_pattern")
+	}
+	// default
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+	if p.Port == "" {
+		p.Port = DefaultPostgresPort
+	}
+	if p.Database == "" {
+		p.Database = p.User
+	}
+	return p, nil
+}
+
+func (p *PostgresChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "postgreschecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	password, err := loadSecret(p.PasswordFile, p.PasswordEnv)
+	if err != nil {
+		return err
+	}
+
+	addr := dialAddr(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, false, false, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("postgres connect failed: %w", err)
+	}
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+	logger.Debug("connected " + addr)
+
+	if p.SSLMode != "disable" && !isUnixAddr(p.Host) { // SSL is not used over a unix socket, like libpq
+		if conn, err = p.negotiateSSL(ctx, conn); err != nil {
+			return err
+		}
+	}
+	pc := &pgConn{w: conn, r: bufio.NewReader(conn)}
+	if err := pc.startup(p.User, password, p.Database); err != nil {
+		return fmt.Errorf("postgres startup failed: %w", err)
+	}
+	logger.Debug("ready for query")
+	defer pc.send('X', nil) // Terminate
+
+	if p.Query == "" {
+		return nil
+	}
+	value, err := pc.query(p.Query)
+	if err != nil {
+		return fmt.Errorf("postgres query failed: %w", err)
+	}
+	logger.Debug("query result " + value)
+	if p.Expect != nil && value != *p.Expect {
+		return fmt.Errorf("postgres unexpected result: %s", value)
+	}
+	if p.ExpectPattern != nil && !p.ExpectPattern.MatchString(value) {
+		return fmt.Errorf("postgres result %s does not match expect_pattern %s", value, p.ExpectPattern.String())
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS config for the sslmode as libpq.
+// prefer and require encrypt the connection without verification.
+// verify-ca verifies the certificate chain, and verify-full verifies the host name too.
+func (p *PostgresChecker) tlsConfig() *tls.Config {
+	switch p.SSLMode {
+	case "verify-full":
+		return &tls.Config{ServerName: p.Host, RootCAs: p.RootCAs}
+	case "verify-ca":
+		return &tls.Config{
+			ServerName:         p.Host,
+			InsecureSkipVerify: true,
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 {
+					return errors.New("no certificate")
+				}
+				opts := x509.VerifyOptions{Roots: p.RootCAs, Intermediates: x509.NewCertPool()}
+				for _, cert := range cs.PeerCertificates[1:] {
+					opts.Intermediates.AddCert(cert)
+				}
+				_, err := cs.PeerCertificates[0].Verify(opts)
+				return err
+			},
+		}
+	default:
+		return &tls.Config{ServerName: p.Host, InsecureSkipVerify: true}
+	}
+}
+
+// negotiateSSL sends SSLRequest, and upgrades the connection to TLS if the server accepts.
+func (p *PostgresChecker) negotiateSSL(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	req := binary.BigEndian.AppendUint32(nil, 8)
+	req = binary.BigEndian.AppendUint32(req, pgSSLRequestCode)
+	if _, err := conn.Write(req); err != nil {
+		return conn, fmt.Errorf("postgres SSLRequest failed: %w", err)
+	}
+	var res [1]byte
+	if _, err := io.ReadFull(conn, res[:]); err != nil {
+		return conn, fmt.Errorf("postgres SSLRequest failed: %w", err)
+	}
+	switch res[0] {
+	case 'S':
+		tc := tls.Client(conn, p.tlsConfig())
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return conn, fmt.Errorf("postgres TLS handshake failed: %w", err)
+		}
+		return tc, nil
+	case 'N':
+		if p.SSLMode != "prefer" {
+			return conn, errors.New("postgres server does not support SSL")
+		}
+		return conn, nil
+	default:
+		return conn, fmt.Errorf("postgres unexpected SSLRequest response: %q", res[0])
+	}
+}
+
+// pgConn is a minimal PostgreSQL frontend.
+type pgConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+// send sends a message. typ 0 means a message without type (StartupMessage).
+func (c *pgConn) send(typ byte, payload []byte) error {
+	var b []byte
+	if typ != 0 {
+		b = append(b, typ)
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)+4))
+	b = append(b, payload...)
+	_, err := c.w.Write(b)
+	return err
+}
+
+func (c *pgConn) recv() (byte, []byte, error) {
+	var h [5]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(h[1:])
+	if n < 4 || n > 1<<24 {
+		return 0, nil, fmt.Errorf("invalid message length %d", n)
+	}
+	payload := make([]byte, n-4)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	if h[0] == 'E' {
+		return h[0], payload, pgError(payload)
+	}
+	return h[0], payload, nil
+}
+
+func (c *pgConn) startup(user, password, database string) error {
+	msg := binary.BigEndian.AppendUint32(nil, pgProtocolVersion)
+	for _, kv := range [][2]string{{"user", user}, {"database", database}, {"application_name", "greenlight"}} {
+		msg = append(msg, kv[0]...)
+		msg = append(msg, 0)
+		msg = append(msg, kv[1]...)
+		msg = append(msg, 0)
+	}
+	msg = append(msg, 0)
+	if err := c.send(0, msg); err != nil {
+		return err
+	}
+
+	var scram *scramClient
+	for {
+		typ, payload, err := c.recv()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'R':
+			if len(payload) < 4 {
+				return errors.New("invalid authentication message")
+			}
+			code, data := binary.BigEndian.Uint32(payload), payload[4:]
+			switch code {
+			case 0: // AuthenticationOk
+			case 3: // AuthenticationCleartextPassword
+				if err := c.send('p', append([]byte(password), 0)); err != nil {
+					return err
+				}
+			case 5: // AuthenticationMD5Password
+				if len(data) < 4 {
+					return errors.New("invalid md5 salt")
+				}
+				if err := c.send('p', append([]byte(pgMD5Password(user, password, data[:4])), 0)); err != nil {
+					return err
+				}
+			case 10: // AuthenticationSASL
+				if !bytes.Contains(data, []byte("SCRAM-SHA-256\x00")) {
+					return fmt.Errorf("unsupported SASL mechanisms: %q", data)
+				}
+				scram = newSCRAMClient(password)
+				first := scram.clientFirst()
+				msg := append([]byte("SCRAM-SHA-256"), 0)
+				msg = binary.BigEndian.AppendUint32(msg, uint32(len(first)))
+				msg = append(msg, first...)
+				if err := c.send('p', msg); err != nil {
+					return err
+				}
+			case 11: // AuthenticationSASLContinue
+				if scram == nil {
+					return errors.New("unexpected SASLContinue")
+				}
+				final, err := scram.clientFinal(string(data))
+				if err != nil {
+					return err
+				}
+				if err := c.send('p', []byte(final)); err != nil {
+					return err
+				}
+			case 12: // AuthenticationSASLFinal
+				if scram == nil {
+					return errors.New("unexpected SASLFinal")
+				}
+				if err := scram.verifyServerFinal(string(data)); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unsupported authentication method %d", code)
+			}
+		case 'Z': // ReadyForQuery
+			return nil
+		case 'S', 'K', 'N': // ParameterStatus, BackendKeyData, NoticeResponse
+		default:
+			return fmt.Errorf("unexpected message %q", typ)
+		}
+	}
+}
+
+// query runs the query by the simple query protocol, and returns the first column of the first row.
+func (c *pgConn) query(q string) (string, error) {
+	if err := c.send('Q', append([]byte(q), 0)); err != nil {
+		return "", err
+	}
+	var value string
+	var rows int
+	var qerr error
+	for {
+		typ, payload, err := c.recv()
+		if err != nil {
+			if typ != 'E' {
+				return "", err
+			}
+			qerr = err // wait for ReadyForQuery
+			continue
+		}
+		switch typ {
+		case 'D': // DataRow
+			if rows == 0 {
+				if value, err = pgFirstColumn(payload); err != nil {
+					return "", err
+				}
+			}
+			rows++
+		case 'Z':
+			if qerr != nil {
+				return "", qerr
+			}
+			if rows == 0 {
+				return "", errors.New("no rows")
+			}
+			return value, nil
+		case 'T', 'C', 'I', 'N', 'S': // RowDescription, CommandComplete, EmptyQueryResponse, NoticeResponse, ParameterStatus
+		default:
+			return "", fmt.Errorf("unexpected message %q", typ)
+		}
+	}
+}
+
+func pgFirstColumn(payload []byte) (string, error) {
+	if len(payload) < 2 || binary.BigEndian.Uint16(payload) == 0 {
+		return "", errors.New("no columns")
+	}
+	if len(payload) < 6 {
+		return "", errors.New("invalid DataRow")
+	}
+	n := int32(binary.BigEndian.Uint32(payload[2:]))
+	if n < 0 {
+		return "", nil // NULL
+	}
+	if len(payload) < 6+int(n) {
+		return "", errors.New("invalid DataRow")
+	}
+	return string(payload[6 : 6+n]), nil
+}
+
+// pgError converts an ErrorResponse to an error.
+func pgError(payload []byte) error {
+	var severity, code, message string
+	for _, f := range bytes.Split(payload, []byte{0}) {
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case 'S':
+			severity = string(f[1:])
+		case 'C':
+			code = string(f[1:])
+		case 'M':
+			message = string(f[1:])
+		}
+	}
+	return fmt.Errorf("%s: %s (SQLSTATE %s)", severity, message, code)
+}
+
+func pgMD5Password(user, password string, salt []byte) string {
+	h := md5.Sum([]byte(password + user))
+	h = md5.Sum(append([]byte(hex.EncodeToString(h[:])), salt...))
+	return "md5" + hex.EncodeToString(h[:])
+}
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 5802, RFC 7677).
+type scramClient struct {
+	password        string
+	nonce           string
+	clientFirstBare string
+	authMessage     string
+	saltedPassword  []byte
+}
+
+func newSCRAMClient(password string) *scramClient {
+	b := make([]byte, 18)
+	rand.Read(b)
+	return &scramClient{
+		password: password,
+		nonce:    base64.RawStdEncoding.EncodeToString(b),
+	}
+}
+
+func (s *scramClient) clientFirst() string {
+	// PostgreSQL ignores the user name in SCRAM, uses the one in the startup message.
+	s.clientFirstBare = "n=,r=" + s.nonce
+	return "n,," + s.clientFirstBare
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttributes(serverFirst)
+	nonce, salt64, iter := attrs["r"], attrs["s"], attrs["i"]
+	if !strings.HasPrefix(nonce, s.nonce) {
+		return "", errors.New("SCRAM server nonce mismatch")
+	}
+	salt, err := base64.StdEncoding.DecodeString(salt64)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	i, err := strconv.Atoi(iter)
+	if err != nil || i < 1 {
+		return "", fmt.Errorf("invalid SCRAM iteration count: %s", iter)
+	}
+	s.saltedPassword = scramHi([]byte(s.password), salt, i)
+	withoutProof := "c=biws,r=" + nonce
+	s.authMessage = s.clientFirstBare + "," + serverFirst + "," + withoutProof
+
+	clientKey := hmacSHA256(s.saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	proof := hmacSHA256(storedKey[:], s.authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (s *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := scramAttributes(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("SCRAM authentication failed: %s", e)
+	}
+	serverKey := hmacSHA256(s.saltedPassword, "Server Key")
+	expected := base64.StdEncoding.EncodeToString(hmacSHA256(serverKey, s.authMessage))
+	if !hmac.Equal([]byte(attrs["v"]), []byte(expected)) {
+		return errors.New("SCRAM server signature mismatch")
+	}
+	return nil
+}
+
+func scramAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// scramHi is PBKDF2-HMAC-SHA256 with a single block.
+func scramHi(password, salt []byte, iter int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	result := bytes.Clone(u)
+	for n := 1; n < iter; n++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for i := range result {
+			result[i] ^= u[i]
+		}
+	}
+	return result
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,273 @@
+package greenlight_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// fakePostgres is an in-process server speaking a subset of the PostgreSQL protocol.
+type fakePostgres struct {
+	auth     string // trust, password, md5, or scram
+	user     string
+	password string
+	recovery string
+	tls      *tls.Config // accepts SSLRequest if not nil
+}
+
+func (s *fakePostgres) run(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := s.serve(conn); err != nil && err != io.EOF {
+					t.Log("fake postgres:", err)
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func pgSend(w io.Writer, typ byte, payload []byte) error {
+	b := []byte{typ}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)+4))
+	_, err := w.Write(append(b, payload...))
+	return err
+}
+
+func pgRecv(r io.Reader) (byte, []byte, error) {
+	var h [5]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(h[1:])-4)
+	_, err := io.ReadFull(r, payload)
+	return h[0], payload, err
+}
+
+func pgAuth(code uint32, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, code), data...)
+}
+
+func pgErrorResponse(msg string) []byte {
+	return []byte("SFATAL\x00C28P01\x00M" + msg + "\x00\x00")
+}
+
+func (s *fakePostgres) serve(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	var params map[string]string
+	for params == nil {
+		var h [8]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return err
+		}
+		body := make([]byte, binary.BigEndian.Uint32(h[:4])-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint32(h[4:]) == 80877103 { // SSLRequest
+			if s.tls == nil {
+				conn.Write([]byte{'N'})
+				continue
+			}
+			conn.Write([]byte{'S'})
+			tc := tls.Server(conn, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return err
+			}
+			conn, r = tc, bufio.NewReader(tc)
+			continue
+		}
+		params = make(map[string]string)
+		kv := strings.Split(string(body), "\x00")
+		for i := 0; i+1 < len(kv); i += 2 {
+			params[kv[i]] = kv[i+1]
+		}
+	}
+	if params["user"] != s.user {
+		return pgSend(conn, 'E', pgErrorResponse("role does not exist"))
+	}
+
+	switch s.auth {
+	case "password":
+		pgSend(conn, 'R', pgAuth(3, nil))
+		_, p, err := pgRecv(r)
+		if err != nil {
+			return err
+		}
+		if string(p) != s.password+"\x00" {
+			return pgSend(conn, 'E', pgErrorResponse("password authentication failed"))
+		}
+	case "md5":
+		salt := []byte{1, 2, 3, 4}
+		pgSend(conn, 'R', pgAuth(5, salt))
+		_, p, err := pgRecv(r)
+		if err != nil {
+			return err
+		}
+		h := md5.Sum([]byte(s.password + s.user))
+		h = md5.Sum(append([]byte(hex.EncodeToString(h[:])), salt...))
+		if string(p) != "md5"+hex.EncodeToString(h[:])+"\x00" {
+			return pgSend(conn, 'E', pgErrorResponse("password authentication failed"))
+		}
+	case "scram":
+		if err := s.scram(conn, r); err != nil {
+			return err
+		}
+	}
+	pgSend(conn, 'R', pgAuth(0, nil))
+	pgSend(conn, 'S', []byte("server_version\x0016.0\x00"))
+	pgSend(conn, 'Z', []byte{'I'})
+
+	for {
+		typ, p, err := pgRecv(r)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'X':
+			return nil
+		case 'Q':
+			if q := strings.TrimSuffix(string(p), "\x00"); q == "SELECT pg_is_in_recovery()" {
+				row := binary.BigEndian.AppendUint16(nil, 1)
+				row = binary.BigEndian.AppendUint32(row, uint32(len(s.recovery)))
+				row = append(row, s.recovery...)
+				pgSend(conn, 'T', []byte{0, 0})
+				pgSend(conn, 'D', row)
+				pgSend(conn, 'C', []byte("SELECT 1\x00"))
+			} else {
+				pgSend(conn, 'E', []byte("SERROR\x00C42601\x00Msyntax error\x00\x00"))
+			}
+			pgSend(conn, 'Z', []byte{'I'})
+		}
+	}
+}
+
+func (s *fakePostgres) scram(conn net.Conn, r io.Reader) error {
+	pgSend(conn, 'R', pgAuth(10, []byte("SCRAM-SHA-256\x00\x00")))
+	_, p, err := pgRecv(r)
+	if err != nil {
+		return err
+	}
+	mech, rest, _ := bytes.Cut(p, []byte{0})
+	if string(mech) != "SCRAM-SHA-256" {
+		return fmt.Errorf("unexpected mechanism %s", mech)
+	}
+	clientFirstBare := strings.TrimPrefix(string(rest[4:]), "n,,")
+	clientNonce := clientFirstBare[strings.Index(clientFirstBare, "r=")+2:]
+	salt := []byte("saltsalt")
+	serverFirst := fmt.Sprintf("r=%sserver,s=%s,i=4096", clientNonce, base64.StdEncoding.EncodeToString(salt))
+	pgSend(conn, 'R', pgAuth(11, []byte(serverFirst)))
+
+	_, p, err = pgRecv(r)
+	if err != nil {
+		return err
+	}
+	clientFinal := string(p)
+	i := strings.LastIndex(clientFinal, ",p=")
+	proof, _ := base64.StdEncoding.DecodeString(clientFinal[i+3:])
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinal[:i]
+
+	mac := func(key []byte, s string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(s))
+		return h.Sum(nil)
+	}
+	salted := greenlight.ScramHi([]byte(s.password), salt, 4096)
+	clientKey := mac(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	sig := mac(storedKey[:], authMessage)
+	for i := range sig {
+		sig[i] ^= proof[i]
+	}
+	if got := sha256.Sum256(sig); !hmac.Equal(got[:], storedKey[:]) {
+		return pgSend(conn, 'E', pgErrorResponse("password authentication failed"))
+	}
+	serverSig := mac(mac(salted, "Server Key"), authMessage)
+	return pgSend(conn, 'R', pgAuth(12, []byte("v="+base64.StdEncoding.EncodeToString(serverSig))))
+}
+
+func TestPostgresChecker(t *testing.T) {
+	t.Setenv("PGPASSWORD", "secret")
+	caPEM, certPEM, keyPEM := newTestCertificate(t, 60)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{cert}}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		server    fakePostgres
+		cfg       greenlight.PostgresCheckConfig
+		expectErr bool
+	}{
+		{"trust", fakePostgres{auth: "trust", user: "app"}, greenlight.PostgresCheckConfig{User: "app"}, false},
+		{"password", fakePostgres{auth: "password", user: "app", password: "secret"}, greenlight.PostgresCheckConfig{User: "app", PasswordEnv: "PGPASSWORD"}, false},
+		{"md5", fakePostgres{auth: "md5", user: "app", password: "secret"}, greenlight.PostgresCheckConfig{User: "app", PasswordEnv: "PGPASSWORD"}, false},
+		{"md5 wrong password", fakePostgres{auth: "md5", user: "app", password: "other"}, greenlight.PostgresCheckConfig{User: "app", PasswordEnv: "PGPASSWORD"}, true},
+		{"scram", fakePostgres{auth: "scram", user: "app", password: "secret"}, greenlight.PostgresCheckConfig{User: "app", PasswordEnv: "PGPASSWORD"}, false},
+		{"scram wrong password", fakePostgres{auth: "scram", user: "app", password: "other"}, greenlight.PostgresCheckConfig{User: "app", PasswordEnv: "PGPASSWORD"}, true},
+		{"unknown user", fakePostgres{auth: "trust", user: "app"}, greenlight.PostgresCheckConfig{User: "nobody"}, true},
+		{"primary", fakePostgres{auth: "trust", user: "app", recovery: "f"}, greenlight.PostgresCheckConfig{User: "app", Query: "SELECT pg_is_in_recovery()", Expect: "f"}, false},
+		{"in recovery", fakePostgres{auth: "trust", user: "app", recovery: "t"}, greenlight.PostgresCheckConfig{User: "app", Query: "SELECT pg_is_in_recovery()", Expect: "f"}, true},
+		{"query pattern", fakePostgres{auth: "trust", user: "app", recovery: "t"}, greenlight.PostgresCheckConfig{User: "app", Query: "SELECT pg_is_in_recovery()", ExpectPattern: "^[tf]$"}, false},
+		{"query error", fakePostgres{auth: "trust", user: "app"}, greenlight.PostgresCheckConfig{User: "app", Query: "SELEC 1"}, true},
+		{"ssl required", fakePostgres{auth: "trust", user: "app"}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "require"}, true},
+		{"ssl prefer", fakePostgres{auth: "trust", user: "app", tls: serverTLS}, greenlight.PostgresCheckConfig{User: "app"}, false},
+		{"ssl require self-signed", fakePostgres{auth: "trust", user: "app", tls: serverTLS}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "require"}, false},
+		{"ssl verify-ca", fakePostgres{auth: "trust", user: "app", tls: serverTLS}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "verify-ca", CAFile: caFile}, false},
+		{"ssl verify-ca unknown CA", fakePostgres{auth: "trust", user: "app", tls: serverTLS}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "verify-ca"}, true},
+		{"ssl verify-full host mismatch", fakePostgres{auth: "trust", user: "app", tls: serverTLS}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "verify-full", CAFile: caFile}, true},
+		{"ssl verify-full not supported", fakePostgres{auth: "trust", user: "app"}, greenlight.PostgresCheckConfig{User: "app", SSLMode: "verify-full", CAFile: caFile}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _ := net.SplitHostPort(tt.server.run(t))
+			tt.cfg.Host, tt.cfg.Port = host, port
+			checker, err := greenlight.NewPostgresChecker(&greenlight.CheckConfig{
+				Name:     tt.name,
+				Timeout:  time.Second,
+				Postgres: &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package greenlight
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultProcRoot                 = "/proc"
+	DefaultProcessCPUSampleDuration = 1 * time.Second
+)
+
+// clockTicks returns USER_HZ, the unit of the CPU times in /proc/<pid>/stat.
+// It is AT_CLKTCK in the auxiliary vector of the running process, which sysconf(_SC_CLK_TCK) returns.
+// The kernel fixes it to 100 on most architectures, so 100 is assumed if the vector is not readable.
+var clockTicks = sync.OnceValue(func() float64 {
+	const atClkTck = 17
+	b, err := os.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return 100
+	}
+	size := strconv.IntSize / 8
+	for i := 0; i+2*size <= len(b); i += 2 * size {
+		var k, v uint64
+		if size == 8 {
+			k, v = binary.NativeEndian.Uint64(b[i:]), binary.NativeEndian.Uint64(b[i+size:])
+		} else {
+			k, v = uint64(binary.NativeEndian.Uint32(b[i:])), uint64(binary.NativeEndian.Uint32(b[i+size:]))
+		}
+		if k == atClkTck && v > 0 {
+			return float64(v)
+		}
+	}
+	return 100
+})
+
+type ProcessCheckConfig struct {
+	PIDFile    string   `yaml:"pidfile"`
+	Name       string   `yaml:"name"`
+	Cmdline    string   `yaml:"cmdline"`
+	Child      bool     `yaml:"child"`
+	MaxRSS     ByteSize `yaml:"max_rss"`
+	MaxFDs     int      `yaml:"max_fds"`
+	MaxThreads int      `yaml:"max_threads"`
+	MaxCPU     float64  `yaml:"max_cpu"`
+	ProcRoot   string   `yaml:"proc_root"`
+}
+
+type ProcessChecker struct {
+	PIDFile        string
+	NamePattern    *regexp.Regexp
+	CmdlinePattern *regexp.Regexp
+	Child          bool
+	MaxRSS         int64
+	MaxFDs         int
+	MaxThreads     int
+	MaxCPU         float64
+	ProcRoot       string
+	Timeout        time.Duration
+
+	name string
+}
+
+func (p *ProcessChecker) Name() string {
+	return p.name
+}
+
+func NewProcessChecker(cfg *CheckConfig) (*ProcessChecker, error) {
+	c := cfg.Process
+	p := &ProcessChecker{
+		name:       cfg.Name,
+		Timeout:    cfg.Timeout,
+		PIDFile:    c.PIDFile,
+		Child:      c.Child,
+		MaxRSS:     int64(c.MaxRSS),
+		MaxFDs:     c.MaxFDs,
+		MaxThreads: c.MaxThreads,
+		MaxCPU:     c.MaxCPU,
+		ProcRoot:   c.ProcRoot,
+	}
+	var err error
+	if c.Name != "" {
+		if p.NamePattern, err = regexp.Compile(c.Name); err != nil {
+			return nil, fmt.Errorf("invalid name: %w", err)
+		}
+	}
+	if c.Cmdline != "" {
+		if p.CmdlinePattern, err = regexp.Compile(c.Cmdline); err != nil {
+			return nil, fmt.Errorf("invalid cmdline: %w", err)
+		}
+	}
+	n := 0
+	for _, b := range []bool{p.PIDFile != "", p.NamePattern != nil || p.CmdlinePattern != nil, p.Child} {
+		if b {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("process requires one of pidfile, name/cmdline, or child")
+	}
+	if p.ProcRoot == "" {
+		p.ProcRoot = DefaultProcRoot
+	}
+	return p, nil
+}
+
+func (p *ProcessChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "processchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	pids, err := p.findPIDs(ctx)
+	if err != nil {
+		return err
+	}
+	before := make(map[int]*procStat, len(pids))
+	for _, pid := range pids {
+		st, err := readProcStat(p.ProcRoot, pid)
+		if err != nil {
+			return fmt.Errorf("process %d is not running: %w", pid, err)
+		}
+		if st.State == "Z" || st.State == "X" {
+			return fmt.Errorf("process %d (%s) is a zombie or dead: state %s", pid, st.Comm, st.State)
+		}
+		before[pid] = st
+	}
+
+	var cpu map[int]float64
+	if p.MaxCPU > 0 {
+		if cpu, err = p.sampleCPU(ctx, before); err != nil {
+			return err
+		}
+	}
+	for _, pid := range pids {
+		st := before[pid]
+		l := logger.With(slog.Int("pid", pid), slog.String("comm", st.Comm))
+		if p.MaxRSS > 0 {
+			rss, err := readProcRSS(p.ProcRoot, pid)
+			if err != nil {
+				return fmt.Errorf("process %d read rss failed: %w", pid, err)
+			}
+			l.Debug(fmt.Sprintf("rss %d bytes", rss))
+			if rss > p.MaxRSS {
+				return fmt.Errorf("process %d (%s) rss %d bytes exceeds max_rss %d", pid, st.Comm, rss, p.MaxRSS)
+			}
+		}
+		if p.MaxFDs > 0 {
+			entries, err := os.ReadDir(filepath.Join(p.ProcRoot, strconv.Itoa(pid), "fd"))
+			if err != nil {
+				return fmt.Errorf("process %d read fds failed: %w", pid, err)
+			}
+			l.Debug(fmt.Sprintf("fds %d", len(entries)))
+			if len(entries) > p.MaxFDs {
+				return fmt.Errorf("process %d (%s) open fds %d exceeds max_fds %d", pid, st.Comm, len(entries), p.MaxFDs)
+			}
+		}
+		if p.MaxThreads > 0 {
+			l.Debug(fmt.Sprintf("threads %d", st.Threads))
+			if st.Threads > p.MaxThreads {
+				return fmt.Errorf("process %d (%s) threads %d exceeds max_threads %d", pid, st.Comm, st.Threads, p.MaxThreads)
+			}
+		}
+		if p.MaxCPU > 0 {
+			l.Debug(fmt.Sprintf("cpu %.1f%%", cpu[pid]))
+			if cpu[pid] > p.MaxCPU {
+				return fmt.Errorf("process %d (%s) cpu %.1f%% exceeds max_cpu %.1f%%", pid, st.Comm, cpu[pid], p.MaxCPU)
+			}
+		}
+	}
+	return nil
+}
+
+// sampleCPU returns CPU usage (percent of one CPU) of the processes in a sample duration.
+func (p *ProcessChecker) sampleCPU(ctx context.Context, before map[int]*procStat) (map[int]float64, error) {
+	d := min(DefaultProcessCPUSampleDuration, p.Timeout/2)
+	start := time.Now()
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	elapsed := time.Since(start).Seconds()
+	usage := make(map[int]float64, len(before))
+	for pid, st := range before {
+		after, err := readProcStat(p.ProcRoot, pid)
+		if err != nil {
+			return nil, fmt.Errorf("process %d is not running: %w", pid, err)
+		}
+		usage[pid] = float64(after.CPUTicks-st.CPUTicks) / clockTicks() / elapsed * 100
+	}
+	return usage, nil
+}
+
+func (p *ProcessChecker) findPIDs(ctx context.Context) ([]int, error) {
+	switch {
+	case p.Child:
+		s, ok := ctx.Value(stateKey).(*State)
+		if !ok || s.ChildPID() == 0 {
+			return nil, errors.New("child command is not running")
+		}
+		return []int{s.ChildPID()}, nil
+	case p.PIDFile != "":
+		b, err := os.ReadFile(p.PIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("read pidfile failed: %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("invalid pidfile %s: %q", p.PIDFile, b)
+		}
+		return []int{pid}, nil
+	}
+
+	entries, err := os.ReadDir(p.ProcRoot)
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join(p.ProcRoot, e.Name())
+		if p.NamePattern != nil {
+			comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+			if err != nil || !p.NamePattern.Match(bytes.TrimSpace(comm)) {
+				continue
+			}
+		}
+		if p.CmdlinePattern != nil {
+			cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+			if err != nil {
+				continue
+			}
+			cmdline = bytes.TrimRight(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '}), " ")
+			if !p.CmdlinePattern.Match(cmdline) {
+				continue
+			}
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		return nil, errors.New("no process found")
+	}
+	return pids, nil
+}
+
+type procStat struct {
+	Comm     string
+	State    string
+	Threads  int
+	CPUTicks int64 // utime + stime
+}
+
+// readProcStat parses /proc/<pid>/stat.
+func readProcStat(root string, pid int) (*procStat, error) {
+	b, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// comm may contain spaces and parentheses. e.g. "1 (my (app)) S 0 ..."
+	lp, rp := bytes.IndexByte(b, '('), bytes.LastIndexByte(b, ')')
+	if lp < 0 || rp < lp {
+		return nil, errors.New("invalid stat")
+	}
+	fields := strings.Fields(string(b[rp+1:]))
+	// fields[0] is the 3rd field (state) in proc(5).
+	if len(fields) < 18 {
+		return nil, errors.New("invalid stat")
+	}
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	return &procStat{
+		Comm:     string(b[lp+1 : rp]),
+		State:    fields[0],
+		Threads:  threads,
+		CPUTicks: utime + stime,
+	}, nil
+}
+
+// readProcRSS returns VmRSS in /proc/<pid>/status in bytes.
+func readProcRSS(root string, pid int) (int64, error) {
+	b, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(line, "VmRSS:"); ok {
+			kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "kB")), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid VmRSS: %s", v)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, nil // kernel threads have no VmRSS
+}
@@ -0,0 +1,130 @@
+package greenlight_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestProcessChecker(t *testing.T) {
+	// testdata/proc: 4242 "my (app)" sleeping, rss 20MiB, 3 fds, 3 threads,
+	// 4243 "defunct" zombie.
+	dir := t.TempDir()
+	pidfile := func(s string) string {
+		path := filepath.Join(dir, strings.TrimSpace(s)+".pid")
+		if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name         string
+		cfg          greenlight.ProcessCheckConfig
+		expectNewErr bool
+		expectErr    bool
+	}{
+		{"name", greenlight.ProcessCheckConfig{Name: `^my \(app\)$`}, false, false},
+		{"cmdline", greenlight.ProcessCheckConfig{Cmdline: "^my-app --queue default$"}, false, false},
+		{"name and cmdline", greenlight.ProcessCheckConfig{Name: "app", Cmdline: "--queue other"}, false, true},
+		{"no process", greenlight.ProcessCheckConfig{Name: "^nothing$"}, false, true},
+		{"zombie", greenlight.ProcessCheckConfig{Name: "^defunct$"}, false, true},
+		{"pidfile", greenlight.ProcessCheckConfig{PIDFile: pidfile("4242\n")}, false, false},
+		{"pidfile zombie", greenlight.ProcessCheckConfig{PIDFile: pidfile("4243")}, false, true},
+		{"pidfile not running", greenlight.ProcessCheckConfig{PIDFile: pidfile("4244")}, false, true},
+		{"pidfile invalid", greenlight.ProcessCheckConfig{PIDFile: pidfile("app")}, false, true},
+		{"pidfile missing", greenlight.ProcessCheckConfig{PIDFile: filepath.Join(dir, "missing.pid")}, false, true},
+		{"max_rss", greenlight.ProcessCheckConfig{Name: "app", MaxRSS: 20 << 20}, false, false},
+		{"max_rss exceeded", greenlight.ProcessCheckConfig{Name: "app", MaxRSS: 10 << 20}, false, true},
+		{"max_fds", greenlight.ProcessCheckConfig{Name: "app", MaxFDs: 3}, false, false},
+		{"max_fds exceeded", greenlight.ProcessCheckConfig{Name: "app", MaxFDs: 2}, false, true},
+		{"max_threads", greenlight.ProcessCheckConfig{Name: "app", MaxThreads: 3}, false, false},
+		{"max_threads exceeded", greenlight.ProcessCheckConfig{Name: "app", MaxThreads: 2}, false, true},
+		{"child not running", greenlight.ProcessCheckConfig{Child: true}, false, true},
+		{"no target", greenlight.ProcessCheckConfig{}, true, false},
+		{"pidfile and name", greenlight.ProcessCheckConfig{PIDFile: pidfile("4242"), Name: "app"}, true, false},
+		{"invalid name", greenlight.ProcessCheckConfig{Name: "("}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ProcRoot = "testdata/proc"
+			checker, err := greenlight.NewProcessChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				Process: &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("expected new error: %v, got: %v", tt.expectNewErr, err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestProcessCheckerMaxCPU(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires /proc")
+	}
+	if ticks := greenlight.ClockTicks(); ticks <= 0 {
+		t.Fatalf("invalid clock ticks %f", ticks)
+	}
+	if out, err := exec.Command("getconf", "CLK_TCK").Output(); err == nil {
+		if want, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64); want != greenlight.ClockTicks() {
+			t.Errorf("clock ticks %f, getconf CLK_TCK %f", greenlight.ClockTicks(), want)
+		}
+	}
+
+	start := func(script string) string {
+		cmd := exec.Command("sh", "-c", script)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		})
+		path := filepath.Join(t.TempDir(), "app.pid")
+		if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name      string
+		script    string
+		maxCPU    float64
+		expectErr bool
+	}{
+		{"idle", "exec sleep 60", 50, false},
+		{"busy", "while :; do :; done", 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewProcessChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second, // samples 500ms
+				Process: &greenlight.ProcessCheckConfig{PIDFile: start(tt.script), MaxCPU: tt.maxCPU},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package greenlight
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultRedisPort = "6379"
+)
+
+type RedisCheckConfig struct {
+	Host               string `yaml:"host"`
+	Port               string `yaml:"port"`
+	Username           string `yaml:"username"`
+	PasswordFile       string `yaml:"password_file"`
+	PasswordEnv        string `yaml:"password_env"`
+	DB                 int    `yaml:"db"`
+	Role               string `yaml:"role"`
+	TLS                bool   `yaml:"tls"`
+	NoCheckCertificate bool   `yaml:"no_check_certificate"`
+}
+
+type RedisChecker struct {
+	Host               string
+	Port               string
+	Username           string
+	PasswordFile       string
+	PasswordEnv        string
+	DB                 int
+	Role               string
+	Timeout            time.Duration
+	TLS                bool
+	NoCheckCertificate bool
+
+	name string
+}
+
+func (p *RedisChecker) Name() string {
+	return p.name
+}
+
+func NewRedisChecker(cfg *CheckConfig) (*RedisChecker, error) {
+	p := &RedisChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		Host:               cfg.Redis.Host,
+		Port:               cfg.Redis.Port,
+		Username:           cfg.Redis.Username,
+		PasswordFile:       cfg.Redis.PasswordFile,
+		PasswordEnv:        cfg.Redis.PasswordEnv,
+		DB:                 cfg.Redis.DB,
+		TLS:                cfg.Redis.TLS,
+		NoCheckCertificate: cfg.Redis.NoCheckCertificate,
+	}
+	if p.Username != "" && p.PasswordFile == "" && p.PasswordEnv == "" {
+		return nil, errors.New("redis username requires password_file or password_env")
+	}
+	if isUnixAddr(p.Host) && p.TLS {
+		return nil, errors.New("redis tls is not supported over a unix socket")
+	}
+	switch role := strings.ToLower(cfg.Redis.Role); role {
+	case "":
+	case "master":
+		p.Role = "master"
+	case "replica", "slave":
+		p.Role = "slave" // INFO replication reports a replica as "slave"
+	default:
+		return nil, fmt.Errorf("invalid role %s: must be master or replica", cfg.Redis.Role)
+	}
+	// default
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+	if p.Port == "" {
+		p.Port = DefaultRedisPort
+	}
+	return p, nil
+}
+
+func (p *RedisChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "redischecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	password, err := loadSecret(p.PasswordFile, p.PasswordEnv)
+	if err != nil {
+		return err
+	}
+	if p.Username != "" && password == "" {
+		return fmt.Errorf("redis password for %s is empty", p.Username)
+	}
+
+	addr := dialAddr(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, p.TLS, p.NoCheckCertificate, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("redis connect failed: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+	logger.Debug("connected " + addr)
+
+	rc := &redisConn{w: conn, r: bufio.NewReader(conn)}
+	if password != "" {
+		args := []string{"AUTH", password}
+		if p.Username != "" {
+			args = []string{"AUTH", p.Username, password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			return fmt.Errorf("redis AUTH failed: %w", err)
+		}
+	}
+	if p.DB != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(p.DB)); err != nil {
+			return fmt.Errorf("redis SELECT %d failed: %w", p.DB, err)
+		}
+	}
+	if res, err := rc.do("PING"); err != nil {
+		return fmt.Errorf("redis PING failed: %w", err)
+	} else if res != "PONG" {
+		return fmt.Errorf("redis unexpected PING response: %s", res)
+	}
+	logger.Debug("PONG")
+
+	if p.Role != "" {
+		info, err := rc.do("INFO", "replication")
+		if err != nil {
+			return fmt.Errorf("redis INFO replication failed: %w", err)
+		}
+		role := redisInfoValue(info, "role")
+		logger.Debug("role " + role)
+		if role != p.Role {
+			return fmt.Errorf("redis role is %s, expected %s", role, p.Role)
+		}
+	}
+	rc.do("QUIT")
+	return nil
+}
+
+// redisInfoValue returns the value of the key in a INFO response.
+func redisInfoValue(info, key string) string {
+	for _, line := range strings.Split(info, "\r\n") {
+		if v, ok := strings.CutPrefix(line, key+":"); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// redisConn is a minimal RESP client.
+type redisConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+// do sends the command and returns the reply as a string.
+// An error reply is returned as an error.
+func (c *redisConn) do(args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.w, b.String()); err != nil {
+		return "", err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid bulk length: %s", line)
+		}
+		if n < 0 {
+			return "", nil // null bulk string
+		}
+		buf := make([]byte, n+2) // with trailing CRLF
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid array length: %s", line)
+		}
+		elems := make([]string, 0, max(n, 0))
+		for i := 0; i < n; i++ {
+			e, err := c.readReply()
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, e)
+		}
+		return strings.Join(elems, "\n"), nil
+	default:
+		return "", fmt.Errorf("unexpected reply: %s", line)
+	}
+}
@@ -0,0 +1,174 @@
+package greenlight_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// fakeRedis is an in-process server speaking a subset of RESP.
+type fakeRedis struct {
+	user     string // ACL user. "default" if empty
+	password string // requires AUTH if not empty
+	role     string
+	reply    string // raw reply to PING, e.g. a broken one
+}
+
+func (s *fakeRedis) run(t *testing.T) (string, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := s.serve(conn); err != nil && err != io.EOF {
+					t.Log("fake redis:", err)
+				}
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return host, port
+}
+
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid command: %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $len
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (s *fakeRedis) serve(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return err
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch cmd {
+		case "AUTH":
+			user, password := "default", args[len(args)-1]
+			if len(args) == 3 {
+				user = args[1]
+			}
+			expectUser := s.user
+			if expectUser == "" {
+				expectUser = "default"
+			}
+			if user != expectUser || password != s.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "SELECT":
+			if n, _ := strconv.Atoi(args[1]); n >= 16 {
+				fmt.Fprint(conn, "-ERR DB index is out of range\r\n")
+				continue
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case "PING":
+			if s.reply != "" {
+				fmt.Fprint(conn, s.reply)
+				continue
+			}
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "INFO":
+			info := fmt.Sprintf("# Replication\r\nrole:%s\r\nconnected_slaves:0\r\n", s.role)
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		case "QUIT":
+			fmt.Fprint(conn, "+OK\r\n")
+			return nil
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+func TestRedisChecker(t *testing.T) {
+	t.Setenv("REDIS_PASSWORD", "secret")
+	t.Setenv("REDIS_WRONG_PASSWORD", "wrong")
+	tests := []struct {
+		name         string
+		server       fakeRedis
+		cfg          greenlight.RedisCheckConfig
+		expectNewErr bool
+		expectErr    bool
+	}{
+		{"ping", fakeRedis{role: "master"}, greenlight.RedisCheckConfig{}, false, false},
+		{"auth", fakeRedis{password: "secret"}, greenlight.RedisCheckConfig{PasswordEnv: "REDIS_PASSWORD"}, false, false},
+		{"auth acl user", fakeRedis{user: "app", password: "secret"}, greenlight.RedisCheckConfig{Username: "app", PasswordEnv: "REDIS_PASSWORD"}, false, false},
+		{"auth failed", fakeRedis{password: "secret"}, greenlight.RedisCheckConfig{PasswordEnv: "REDIS_WRONG_PASSWORD"}, false, true},
+		{"auth required", fakeRedis{password: "secret"}, greenlight.RedisCheckConfig{}, false, true},
+		{"username without password", fakeRedis{}, greenlight.RedisCheckConfig{Username: "app"}, true, false},
+		{"empty password for username", fakeRedis{}, greenlight.RedisCheckConfig{Username: "app", PasswordEnv: "REDIS_EMPTY_PASSWORD"}, false, true},
+		{"select", fakeRedis{}, greenlight.RedisCheckConfig{DB: 3}, false, false},
+		{"select out of range", fakeRedis{}, greenlight.RedisCheckConfig{DB: 16}, false, true},
+		{"role master", fakeRedis{role: "master"}, greenlight.RedisCheckConfig{Role: "master"}, false, false},
+		{"role replica", fakeRedis{role: "slave"}, greenlight.RedisCheckConfig{Role: "replica"}, false, false},
+		{"role mismatch", fakeRedis{role: "slave"}, greenlight.RedisCheckConfig{Role: "master"}, false, true},
+		{"invalid role", fakeRedis{}, greenlight.RedisCheckConfig{Role: "sentinel"}, true, false},
+		{"loading", fakeRedis{reply: "-LOADING Redis is loading the dataset in memory\r\n"}, greenlight.RedisCheckConfig{}, false, true},
+		{"unexpected reply", fakeRedis{reply: "+PANG\r\n"}, greenlight.RedisCheckConfig{}, false, true},
+		{"broken reply", fakeRedis{reply: "?\r\n"}, greenlight.RedisCheckConfig{}, false, true},
+		{"empty reply", fakeRedis{reply: "\r\n"}, greenlight.RedisCheckConfig{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Host, tt.cfg.Port = tt.server.run(t)
+			checker, err := greenlight.NewRedisChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				Redis:   &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -4,25 +4,39 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math/rand"
 	"net/http"
 	"sync"
+	"time"
 )
 
 type Responder struct {
-	addr    string
-	current Signal
-	mu      *sync.Mutex
-	ch      chan Signal
-	logger  *slog.Logger
+	addr        string
+	current     Signal
+	forced      Signal
+	forcedUntil time.Time
+	forceTimer  *time.Timer
+	greenSince  time.Time
+	slowStart   time.Duration
+	now         func() time.Time // clock for slow start
+	mu          *sync.Mutex
+	ch          chan Signal
+	events      *eventHub
+	evaluate    func(context.Context) Signal
+	logger      *slog.Logger
 }
 
 func NewResponder(cfg *ResponderConfig) (*Responder, chan Signal) {
 	ch := make(chan Signal, 1)
+	logger := slog.With("module", "responder")
 	return &Responder{
-		addr:   cfg.Addr,
-		mu:     &sync.Mutex{},
-		ch:     ch,
-		logger: slog.With("module", "responder"),
+		addr:      cfg.Addr,
+		slowStart: cfg.SlowStart,
+		now:       time.Now,
+		mu:        &sync.Mutex{},
+		ch:        ch,
+		events:    newEventHub(logger),
+		logger:    logger,
 	}, ch
 }
 
@@ -35,6 +49,7 @@ func (r *Responder) Run(ctx context.Context) error {
 	}
 	go func() {
 		<-ctx.Done()
+		r.events.close()
 		srv.Shutdown(ctx)
 	}()
 	go r.signalLisetener(ctx)
@@ -55,13 +70,96 @@ func (r *Responder) setCurrentSignal(s Signal) {
 	} else {
 		r.logger.Info(fmt.Sprintf("signal changed %s -> %s", r.current, s))
 	}
+	prev := r.effectiveSignal()
 	r.current = s
+	if s == SignalGreen {
+		r.greenSince = r.now()
+	} else {
+		r.greenSince = time.Time{} // the ramp resets whenever the signal leaves green
+	}
+	if cur := r.effectiveSignal(); cur != prev {
+		r.events.publish(eventTypeSignal, eventTypeSignal, SignalEvent{Signal: cur, Previous: prev})
+	}
+}
+
+// effectiveSignal returns the forced signal while it is in effect, or the current signal.
+// r.mu must be held.
+func (r *Responder) effectiveSignal() Signal {
+	if r.forced != SignalNone && time.Now().Before(r.forcedUntil) {
+		return r.forced
+	}
+	return r.current
+}
+
+// weight returns the ratio (0.0-1.0) of health requests to respond OK.
+// It ramps up linearly in slow start duration after the signal turned green.
+func (r *Responder) weight() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.slowStart <= 0 || r.greenSince.IsZero() {
+		return 1
+	}
+	elapsed := r.now().Sub(r.greenSince)
+	if elapsed >= r.slowStart {
+		return 1
+	}
+	return float64(elapsed) / float64(r.slowStart)
 }
 
 func (r *Responder) getCurrentSignal() Signal {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.current
+	return r.effectiveSignal()
+}
+
+// ForceSignal overrides the current signal for the duration.
+// SignalNone clears the override.
+func (r *Responder) ForceSignal(s Signal, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev := r.effectiveSignal()
+	if r.forceTimer != nil {
+		r.forceTimer.Stop()
+		r.forceTimer = nil
+	}
+	if s == SignalNone {
+		r.logger.Info("forced signal cleared")
+		r.forced = SignalNone
+		if r.current != prev {
+			r.events.publish(eventTypeSignal, eventTypeSignal, SignalEvent{Signal: r.current, Previous: prev})
+		}
+		return
+	}
+	r.logger.Info(fmt.Sprintf("signal forced to %s for %s", s, d))
+	r.forced = s
+	r.forcedUntil = time.Now().Add(d)
+	r.forceTimer = time.AfterFunc(d, r.expireForcedSignal)
+	r.events.publish(eventTypeSignal, eventTypeSignal, SignalEvent{Signal: s, Previous: prev, Forced: true})
+}
+
+// expireForcedSignal clears the forced signal when its duration has passed.
+func (r *Responder) expireForcedSignal() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.forced == SignalNone || time.Now().Before(r.forcedUntil) {
+		return // cleared or forced again
+	}
+	r.logger.Info("forced signal expired")
+	prev := r.forced
+	r.forced = SignalNone
+	if r.current != prev {
+		r.events.publish(eventTypeSignal, eventTypeSignal, SignalEvent{Signal: r.current, Previous: prev})
+	}
+}
+
+// SetEvaluator sets a function that evaluates the signal on each health request.
+// It must be called before Run.
+func (r *Responder) SetEvaluator(f func(context.Context) Signal) {
+	r.evaluate = f
+}
+
+func (r *Responder) publish(typ, key string, data any) {
+	r.events.publish(typ, key, data)
 }
 
 func (r *Responder) signalLisetener(ctx context.Context) {
@@ -77,6 +175,13 @@ func (r *Responder) signalLisetener(ctx context.Context) {
 }
 
 func (r *Responder) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/events", r.events.handler())
+	mux.Handle("/", r.healthHandler())
+	return mux
+}
+
+func (r *Responder) healthHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		code := http.StatusOK
 		msg := "OK"
@@ -97,10 +202,28 @@ func (r *Responder) handler() http.Handler {
 
 		w.Header().Set("Content-Type", "text/plain")
 		w.Header().Set("Server", "greenlight/"+Version)
+		if r.evaluate != nil {
+			r.setCurrentSignal(r.evaluate(req.Context()))
+		}
 		s := r.getCurrentSignal()
+		if s != SignalNone {
+			w.Header().Set("X-Greenlight-Signal", string(s))
+		}
 		switch s {
-		case SignalGreen:
-		case SignalYellow, SignalRed:
+		case SignalGreen, SignalYellow:
+			if s == SignalYellow {
+				msg = "OK (degraded)"
+			}
+			if r.slowStart <= 0 {
+				break
+			}
+			weight := r.weight()
+			w.Header().Set("X-Greenlight-Weight", fmt.Sprintf("%d%%", int(weight*100)))
+			if rand.Float64() >= weight {
+				code = http.StatusServiceUnavailable
+				msg = "Service Unavailable (slow start)"
+			}
+		case SignalRed:
 			code = http.StatusServiceUnavailable
 			msg = "Service Unavailable"
 		default:
@@ -0,0 +1,119 @@
+package greenlight_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestSignalResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		signal     greenlight.Signal
+		expectCode int
+		expectBody string
+	}{
+		{"green", greenlight.SignalGreen, http.StatusOK, "OK\n"},
+		{"yellow", greenlight.SignalYellow, http.StatusOK, "OK (degraded)\n"},
+		{"red", greenlight.SignalRed, http.StatusServiceUnavailable, "Service Unavailable\n"},
+		{"no signal", greenlight.SignalNone, http.StatusInternalServerError, "Internal Server Error\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGreenlight(t, &greenlight.Config{})
+			g.SetSignal(tt.signal)
+			w := httptest.NewRecorder()
+			g.ResponderHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.expectCode {
+				t.Errorf("unexpected status: %d", w.Code)
+			}
+			if b := w.Body.String(); b != tt.expectBody {
+				t.Errorf("unexpected body: %q", b)
+			}
+			if h := w.Header().Get("X-Greenlight-Signal"); h != string(tt.signal) {
+				t.Errorf("unexpected X-Greenlight-Signal: %q", h)
+			}
+		})
+	}
+}
+
+func TestSlowStart(t *testing.T) {
+	g := newTestGreenlight(t, &greenlight.Config{
+		Responder: &greenlight.ResponderConfig{SlowStart: 10 * time.Second},
+	})
+	var mu sync.Mutex
+	now := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	g.SetClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	h := g.ResponderHandler()
+	weight := func() string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Header().Get("X-Greenlight-Weight")
+	}
+
+	g.SetSignal(greenlight.SignalGreen)
+	steps := []struct {
+		name    string
+		advance time.Duration
+		signal  greenlight.Signal
+		expect  string
+	}{
+		{"start", 0, "", "0%"},
+		{"midpoint", 5 * time.Second, "", "50%"},
+		{"completed", 5 * time.Second, "", "100%"},
+		{"after completed", time.Hour, "", "100%"},
+		{"red", 0, greenlight.SignalRed, ""},
+		{"reset", time.Second, greenlight.SignalGreen, "0%"},
+		{"ramp again", 2500 * time.Millisecond, "", "25%"},
+		{"degraded", 2500 * time.Millisecond, greenlight.SignalYellow, "100%"},
+		{"green again resets", 2500 * time.Millisecond, greenlight.SignalGreen, "0%"},
+		{"ramp after degraded", 5 * time.Second, "", "50%"},
+	}
+	for _, s := range steps {
+		advance(s.advance)
+		if s.signal != "" {
+			g.SetSignal(s.signal)
+		}
+		if w := weight(); w != s.expect {
+			t.Errorf("%s: unexpected weight: %q", s.name, w)
+		}
+	}
+}
+
+func TestSlowStartRatio(t *testing.T) {
+	g := newTestGreenlight(t, &greenlight.Config{
+		Responder: &greenlight.ResponderConfig{SlowStart: 10 * time.Second},
+	})
+	start := time.Now()
+	g.SetClock(func() time.Time { return start })
+	g.SetSignal(greenlight.SignalGreen)
+	g.SetClock(func() time.Time { return start.Add(5 * time.Second) })
+
+	h := g.ResponderHandler()
+	ok := 0
+	const n = 1000
+	for i := 0; i < n; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code == http.StatusOK {
+			ok++
+		}
+	}
+	// 50% of requests are OK at the midpoint
+	if ok < n*4/10 || ok > n*6/10 {
+		t.Errorf("unexpected ratio of OK at the midpoint: %d/%d", ok, n)
+	}
+}
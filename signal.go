@@ -2,9 +2,15 @@ package greenlight
 
 type Signal string
 
+// SignalGreen is healthy, SignalYellow is degraded but still serving, and SignalRed is unavailable.
 const (
 	SignalNone   Signal = ""
 	SignalGreen  Signal = "green"
 	SignalYellow Signal = "yellow"
 	SignalRed    Signal = "red"
 )
+
+// serving reports whether the responder responds OK for the signal.
+func (s Signal) serving() bool {
+	return s == SignalGreen || s == SignalYellow
+}
@@ -0,0 +1,221 @@
+package greenlight
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultSMTPPort = "25"
+	DefaultSMTPHelo = "localhost"
+)
+
+type SMTPCheckConfig struct {
+	Host               string   `yaml:"host"`
+	Port               string   `yaml:"port"`
+	TLS                bool     `yaml:"tls"`
+	StartTLS           bool     `yaml:"starttls"`
+	NoCheckCertificate bool     `yaml:"no_check_certificate"`
+	Helo               string   `yaml:"helo"`
+	ExpectPattern      string   `yaml:"expect_pattern"`
+	ExpectExtensions   []string `yaml:"expect_extensions"`
+	Username           string   `yaml:"username"`
+	PasswordFile       string   `yaml:"password_file"`
+	PasswordEnv        string   `yaml:"password_env"`
+}
+
+type SMTPChecker struct {
+	Host               string
+	Port               string
+	TLS                bool
+	StartTLS           bool
+	NoCheckCertificate bool
+	Helo               string
+	ExpectPattern      *regexp.Regexp
+	ExpectExtensions   []string
+	Username           string
+	PasswordFile       string
+	PasswordEnv        string
+	Timeout            time.Duration
+
+	name string
+}
+
+func (p *SMTPChecker) Name() string {
+	return p.name
+}
+
+func NewSMTPChecker(cfg *CheckConfig) (*SMTPChecker, error) {
+	c := cfg.SMTP
+	p := &SMTPChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		Host:               c.Host,
+		Port:               c.Port,
+		TLS:                c.TLS,
+		StartTLS:           c.StartTLS,
+		NoCheckCertificate: c.NoCheckCertificate,
+		Helo:               c.Helo,
+		ExpectExtensions:   c.ExpectExtensions,
+		Username:           c.Username,
+		PasswordFile:       c.PasswordFile,
+		PasswordEnv:        c.PasswordEnv,
+	}
+	if p.TLS && p.StartTLS {
+		return nil, errors.New("smtp tls and starttls are exclusive")
+	}
+	if c.ExpectPattern != "" {
+		pt, err := regexp.Compile(c.ExpectPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+		p.ExpectPattern = pt
+	}
+	// default
+	if p.Host == "" {
+		p.Host = "localhost"
+	}
+	if p.Port == "" {
+		p.Port = DefaultSMTPPort
+	}
+	if p.Helo == "" {
+		p.Helo = DefaultSMTPHelo
+	}
+	return p, nil
+}
+
+func (p *SMTPChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "smtpchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	password, err := loadSecret(p.PasswordFile, p.PasswordEnv)
+	if err != nil {
+		return err
+	}
+	if p.Username != "" && !p.TLS && !p.StartTLS && !isLoopback(p.Host) {
+		return errors.New("smtp AUTH requires tls or starttls")
+	}
+
+	addr := net.JoinHostPort(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, p.TLS, p.NoCheckCertificate, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("smtp connect failed: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+	logger.Debug("connected " + addr)
+
+	bc := &smtpBannerConn{Conn: conn}
+	c, err := smtp.NewClient(bc, p.Host)
+	if err != nil {
+		return fmt.Errorf("smtp banner: %w", err)
+	}
+	defer c.Close()
+	banner := bc.banner()
+	logger.Debug("banner " + banner)
+	if p.ExpectPattern != nil && !p.ExpectPattern.MatchString(banner) {
+		return fmt.Errorf("smtp unexpected banner: %s", banner)
+	}
+
+	if err := c.Hello(p.Helo); err != nil {
+		return fmt.Errorf("smtp EHLO: %w", err)
+	}
+	if p.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp STARTTLS is not supported by the server")
+		}
+		// the extensions are discarded, and EHLO is sent again. (RFC 3207)
+		if err := c.StartTLS(&tls.Config{
+			ServerName:         p.Host,
+			InsecureSkipVerify: p.NoCheckCertificate,
+		}); err != nil {
+			return fmt.Errorf("smtp STARTTLS: %w", err)
+		}
+		logger.Debug("STARTTLS succeeded")
+	}
+
+	for _, e := range p.ExpectExtensions {
+		name, params, _ := strings.Cut(e, " ")
+		ok, advertised := c.Extension(name)
+		if !ok {
+			return fmt.Errorf("smtp extension %s is not advertised by the server", name)
+		}
+		for _, param := range strings.Fields(params) {
+			if !slices.Contains(strings.Fields(strings.ToUpper(advertised)), strings.ToUpper(param)) {
+				return fmt.Errorf("smtp extension %s %s is not advertised by the server: %q", name, param, advertised)
+			}
+		}
+	}
+
+	if p.Username != "" {
+		if _, mechanisms := c.Extension("AUTH"); !slices.Contains(strings.Fields(strings.ToUpper(mechanisms)), "PLAIN") {
+			return fmt.Errorf("smtp AUTH PLAIN is not supported by the server: %q", mechanisms)
+		}
+		auth := smtp.PlainAuth("", p.Username, password, p.Host)
+		if p.TLS {
+			auth = smtpImplicitTLSAuth{auth}
+		}
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp AUTH PLAIN: %w", err)
+		}
+		logger.Debug("AUTH succeeded")
+	}
+
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("smtp QUIT: %w", err)
+	}
+	return nil
+}
+
+// smtpBannerConn records the banner, as smtp.NewClient reads it but does not return it.
+type smtpBannerConn struct {
+	net.Conn
+	buf  bytes.Buffer
+	read bool
+}
+
+func (c *smtpBannerConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if !c.read {
+		c.buf.Write(b[:n])
+	}
+	return n, err
+}
+
+// banner returns the message of the banner, and stops the recording.
+func (c *smtpBannerConn) banner() string {
+	c.read = true
+	_, msg, _ := textproto.NewReader(bufio.NewReader(&c.buf)).ReadResponse(220)
+	return msg
+}
+
+// smtpImplicitTLSAuth tells the connection is TLS to smtp.PlainAuth,
+// as smtp.Client detects TLS only by *tls.Conn, which is wrapped by smtpBannerConn.
+type smtpImplicitTLSAuth struct {
+	smtp.Auth
+}
+
+func (a smtpImplicitTLSAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	server.TLS = true
+	return a.Auth.Start(server)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,136 @@
+package greenlight_test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// serveSMTP is a minimal SMTP server with multi-line replies, STARTTLS and AUTH PLAIN.
+func serveSMTP(conn net.Conn, tlsConfig *tls.Config) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220-mail.example.com ESMTP")
+	tc.PrintfLine("220 ready")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO":
+			if arg == "rejected.example.com" {
+				tc.PrintfLine("550 5.7.1 access denied")
+				continue
+			}
+			tc.PrintfLine("250-mail.example.com hello %s", arg)
+			tc.PrintfLine("250-STARTTLS")
+			tc.PrintfLine("250-AUTH PLAIN")
+			tc.PrintfLine("250 8BITMIME")
+		case "STARTTLS":
+			tc.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tc = textproto.NewConn(conn)
+		case "AUTH":
+			_, cred, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(cred)
+			if string(b) == "\x00app\x00secret" {
+				tc.PrintfLine("235 2.7.0 authenticated")
+			} else {
+				tc.PrintfLine("535 5.7.8 authentication failed")
+			}
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func TestSMTPChecker(t *testing.T) {
+	_, certPEM, keyPEM := newTestCertificate(t, 60)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{pair}}
+	// listen serves SMTP, over implicit TLS if tlsConfig is given.
+	listen := func(tlsConfig *tls.Config) (string, string) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go serveSMTP(conn, serverTLS)
+			}
+		}()
+		host, port, _ := net.SplitHostPort(l.Addr().String())
+		return host, port
+	}
+	host, port := listen(nil)
+	_, tlsPort := listen(serverTLS)
+	t.Setenv("SMTP_PASSWORD", "secret")
+	t.Setenv("SMTP_WRONG_PASSWORD", "wrong")
+
+	tests := []struct {
+		name      string
+		cfg       greenlight.SMTPCheckConfig
+		expectErr bool
+	}{
+		{"banner", greenlight.SMTPCheckConfig{ExpectPattern: "ESMTP"}, false},
+		{"banner not match", greenlight.SMTPCheckConfig{ExpectPattern: "Postfix"}, true},
+		{"auth", greenlight.SMTPCheckConfig{Username: "app", PasswordEnv: "SMTP_PASSWORD"}, false},
+		{"auth failed", greenlight.SMTPCheckConfig{Username: "app", PasswordEnv: "SMTP_WRONG_PASSWORD"}, true},
+		{"starttls", greenlight.SMTPCheckConfig{StartTLS: true, NoCheckCertificate: true, Username: "app", PasswordEnv: "SMTP_PASSWORD"}, false},
+		{"starttls untrusted", greenlight.SMTPCheckConfig{StartTLS: true}, true},
+		{"tls", greenlight.SMTPCheckConfig{Port: tlsPort, TLS: true, NoCheckCertificate: true, Username: "app", PasswordEnv: "SMTP_PASSWORD"}, false},
+		{"tls untrusted", greenlight.SMTPCheckConfig{Port: tlsPort, TLS: true}, true},
+		{"ehlo rejected", greenlight.SMTPCheckConfig{Helo: "rejected.example.com"}, true},
+		{"extensions", greenlight.SMTPCheckConfig{ExpectExtensions: []string{"8BITMIME", "AUTH PLAIN", "starttls"}}, false},
+		{"extension not advertised", greenlight.SMTPCheckConfig{ExpectExtensions: []string{"SMTPUTF8"}}, true},
+		{"extension parameter not advertised", greenlight.SMTPCheckConfig{ExpectExtensions: []string{"AUTH LOGIN"}}, true},
+		{"extensions after starttls", greenlight.SMTPCheckConfig{StartTLS: true, NoCheckCertificate: true, ExpectExtensions: []string{"AUTH PLAIN"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Host = host
+			if tt.cfg.Port == "" {
+				tt.cfg.Port = port
+			}
+			checker, err := greenlight.NewSMTPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				SMTP:    &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package greenlight
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type SQLCheckConfig struct {
+	Driver        string `yaml:"driver"`
+	DSN           string `yaml:"dsn"`
+	Query         string `yaml:"query"`
+	Expect        string `yaml:"expect"`
+	ExpectPattern string `yaml:"expect_pattern"`
+	ExpectValue   string `yaml:"expect_value"`
+	ExpectRows    string `yaml:"expect_rows"`
+}
+
+type SQLChecker struct {
+	Driver        string
+	DSN           string
+	Query         string
+	Expect        *string
+	ExpectPattern *regexp.Regexp
+	ExpectValue   func(float64) bool
+	ExpectRows    func(float64) bool
+	Timeout       time.Duration
+
+	name string
+}
+
+func (p *SQLChecker) Name() string {
+	return p.name
+}
+
+func NewSQLChecker(cfg *CheckConfig) (*SQLChecker, error) {
+	p := &SQLChecker{
+		name:    cfg.Name,
+		Timeout: cfg.Timeout,
+		Driver:  cfg.SQL.Driver,
+		DSN:     cfg.SQL.DSN,
+		Query:   cfg.SQL.Query,
+	}
+	drivers := sql.Drivers()
+	if len(drivers) == 0 {
+		return nil, errors.New("sql check is not available: no sql drivers are built in")
+	}
+	if p.Driver == "" {
+		return nil, fmt.Errorf("sql driver is required: one of %v", drivers)
+	}
+	if !slices.Contains(drivers, p.Driver) {
+		return nil, fmt.Errorf("sql driver %q is not built in: available drivers are %v", p.Driver, drivers)
+	}
+	if p.DSN == "" {
+		return nil, errors.New("sql dsn is required")
+	}
+	if p.Query == "" {
+		return nil, errors.New("sql query is required")
+	}
+	var err error
+	if cfg.SQL.Expect != "" {
+		p.Expect = &cfg.SQL.Expect
+	}
+	if pt := cfg.SQL.ExpectPattern; pt != "" {
+		if p.ExpectPattern, err = regexp.Compile(pt); err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern %s: %w", pt, err)
+		}
+	}
+	if v := cfg.SQL.ExpectValue; v != "" {
+		if p.ExpectValue, err = newCompareFunc(v); err != nil {
+			return nil, fmt.Errorf("invalid expect_value: %w", err)
+		}
+	}
+	if v := cfg.SQL.ExpectRows; v != "" {
+		if p.ExpectRows, err = newCompareFunc(v); err != nil {
+			return nil, fmt.Errorf("invalid expect_rows: %w", err)
+		}
+	}
+	return p, nil
+}
+
+func (p *SQLChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "sqlchecker", "driver", p.Driver)
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	// secrets in DSN are expanded from environment variables at each check.
+	db, err := sql.Open(p.Driver, os.ExpandEnv(p.DSN))
+	if err != nil {
+		return fmt.Errorf("sql open failed: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, p.Query)
+	if err != nil {
+		return fmt.Errorf("sql query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var value sql.NullString
+	var n int
+	for rows.Next() {
+		if n == 0 {
+			cols, err := rows.Columns()
+			if err != nil {
+				return fmt.Errorf("sql columns failed: %w", err)
+			}
+			if len(cols) == 0 {
+				return errors.New("sql query returned no columns")
+			}
+			dest := make([]any, len(cols))
+			dest[0] = &value
+			for i := 1; i < len(dest); i++ {
+				dest[i] = new(any)
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return fmt.Errorf("sql scan failed: %w", err)
+			}
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sql query failed: %w", err)
+	}
+	logger.Debug("query result", "rows", n, "value", value.String, "null", !value.Valid)
+
+	if p.ExpectRows != nil && !p.ExpectRows(float64(n)) {
+		return fmt.Errorf("sql unexpected rows: %d", n)
+	}
+	if p.Expect == nil && p.ExpectPattern == nil && p.ExpectValue == nil {
+		return nil
+	}
+	if n == 0 {
+		return errors.New("sql query returned no rows")
+	}
+	if !value.Valid {
+		return errors.New("sql unexpected result: NULL")
+	}
+	if p.Expect != nil && value.String != *p.Expect {
+		return fmt.Errorf("sql unexpected result: %s", value.String)
+	}
+	if p.ExpectPattern != nil && !p.ExpectPattern.MatchString(value.String) {
+		return fmt.Errorf("sql result %s does not match expect_pattern %s", value.String, p.ExpectPattern.String())
+	}
+	if p.ExpectValue != nil {
+		v, err := strconv.ParseFloat(value.String, 64)
+		if err != nil {
+			return fmt.Errorf("sql result %s is not a number", value.String)
+		}
+		if !p.ExpectValue(v) {
+			return fmt.Errorf("sql unexpected result: %s", value.String)
+		}
+	}
+	return nil
+}
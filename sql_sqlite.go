@@ -0,0 +1,6 @@
+//go:build !no_sqlite
+
+package greenlight
+
+// The sqlite driver is built in by default. Build with -tags no_sqlite to drop it.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,81 @@
+//go:build !no_sqlite
+
+package greenlight_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestSQLChecker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE jobs (name TEXT, status TEXT, pending INTEGER)",
+		"INSERT INTO jobs VALUES ('import', 'ok', 3), ('export', 'ok', 120)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+	t.Setenv("STATE_DB", path)
+
+	tests := []struct {
+		name      string
+		cfg       greenlight.SQLCheckConfig
+		expectErr bool
+	}{
+		{"query", greenlight.SQLCheckConfig{Query: "SELECT 1"}, false},
+		{"expect", greenlight.SQLCheckConfig{Query: "SELECT status FROM jobs WHERE name = 'import'", Expect: "ok"}, false},
+		{"expect not match", greenlight.SQLCheckConfig{Query: "SELECT status FROM jobs WHERE name = 'import'", Expect: "ng"}, true},
+		{"expect_pattern", greenlight.SQLCheckConfig{Query: "SELECT name FROM jobs ORDER BY name", ExpectPattern: "^ex"}, false},
+		{"expect_value", greenlight.SQLCheckConfig{Query: "SELECT max(pending) FROM jobs", ExpectValue: "< 500"}, false},
+		{"expect_value exceeded", greenlight.SQLCheckConfig{Query: "SELECT max(pending) FROM jobs", ExpectValue: "<= 100"}, true},
+		{"expect_rows", greenlight.SQLCheckConfig{Query: "SELECT * FROM jobs", ExpectRows: "2"}, false},
+		{"expect_rows not match", greenlight.SQLCheckConfig{Query: "SELECT * FROM jobs WHERE status != 'ok'", ExpectRows: "> 0"}, true},
+		{"no rows", greenlight.SQLCheckConfig{Query: "SELECT status FROM jobs WHERE name = 'none'", Expect: "ok"}, true},
+		{"null", greenlight.SQLCheckConfig{Query: "SELECT NULL", ExpectPattern: ".*"}, true},
+		{"query error", greenlight.SQLCheckConfig{Query: "SELECT * FROM missing"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Driver = "sqlite"
+			tt.cfg.DSN = "file:${STATE_DB}?mode=ro"
+			checker, err := greenlight.NewSQLChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				SQL:     &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestNewSQLCheckerInvalidDriver(t *testing.T) {
+	for _, driver := range []string{"", "nosuchdriver"} {
+		_, err := greenlight.NewSQLChecker(&greenlight.CheckConfig{
+			SQL: &greenlight.SQLCheckConfig{Driver: driver, DSN: "x", Query: "SELECT 1"},
+		})
+		if err == nil {
+			t.Errorf("expected error for driver %q", driver)
+		} else if !strings.Contains(err.Error(), "[sqlite]") {
+			t.Errorf("error should list the available drivers: %s", err)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package greenlight
 
+import "sync"
+
 type phase string
 
 type phaseKeyType string
@@ -27,6 +29,9 @@ const (
 type State struct {
 	Phase      phase
 	CheckIndex numofCheckers
+
+	mu       sync.Mutex
+	childPID int
 }
 
 func newState() *State {
@@ -37,6 +42,8 @@ func newState() *State {
 }
 
 func (s *State) NextPhase() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	switch s.Phase {
 	case phaseStartUp:
 		s.Phase = phaseRunning
@@ -45,3 +52,30 @@ func (s *State) NextPhase() {
 		s.CheckIndex = 0
 	}
 }
+
+func (s *State) setCheckIndex(i numofCheckers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CheckIndex = i
+}
+
+// snapshot returns the current phase and check index.
+// It is safe to call from other goroutines.
+func (s *State) snapshot() (phase, numofCheckers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Phase, s.CheckIndex
+}
+
+func (s *State) setChildPID(pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.childPID = pid
+}
+
+// ChildPID returns the pid of the child command. 0 means not running.
+func (s *State) ChildPID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.childPID
+}
@@ -0,0 +1,264 @@
+package greenlight
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultSystemCPUSampleDuration = 1 * time.Second
+)
+
+type SystemCheckConfig struct {
+	MaxLoadPerCPU   float64       `yaml:"max_load_per_cpu"`
+	LoadAverage     int           `yaml:"load_average"`
+	MinMemAvailable SizeThreshold `yaml:"min_mem_available"`
+	MaxSwapUsed     SizeThreshold `yaml:"max_swap_used"`
+	MaxCPUSteal     float64       `yaml:"max_cpu_steal"`
+	ProcRoot        string        `yaml:"proc_root"`
+}
+
+type SystemChecker struct {
+	MaxLoadPerCPU   float64
+	LoadAverage     int // 1, 5, or 15 minutes
+	MinMemAvailable *sizeLimit
+	MaxSwapUsed     *sizeLimit
+	MaxCPUSteal     float64
+	ProcRoot        string
+	Timeout         time.Duration
+
+	name string
+}
+
+func (p *SystemChecker) Name() string {
+	return p.name
+}
+
+func NewSystemChecker(cfg *CheckConfig) (*SystemChecker, error) {
+	c := cfg.System
+	p := &SystemChecker{
+		name:          cfg.Name,
+		Timeout:       cfg.Timeout,
+		MaxLoadPerCPU: c.MaxLoadPerCPU,
+		LoadAverage:   c.LoadAverage,
+		MaxCPUSteal:   c.MaxCPUSteal,
+		ProcRoot:      c.ProcRoot,
+	}
+	var err error
+	if p.MinMemAvailable, err = parseSizeThreshold(c.MinMemAvailable, true); err != nil {
+		return nil, fmt.Errorf("invalid min_mem_available: %w", err)
+	}
+	if p.MaxSwapUsed, err = parseSizeThreshold(c.MaxSwapUsed, true); err != nil {
+		return nil, fmt.Errorf("invalid max_swap_used: %w", err)
+	}
+	if p.MaxLoadPerCPU == 0 && p.MinMemAvailable == nil && p.MaxSwapUsed == nil && p.MaxCPUSteal == 0 {
+		return nil, errors.New("system requires one of max_load_per_cpu, min_mem_available, max_swap_used, or max_cpu_steal")
+	}
+	// default
+	switch p.LoadAverage {
+	case 0:
+		p.LoadAverage = 1
+	case 1, 5, 15:
+	default:
+		return nil, fmt.Errorf("invalid load_average %d: must be 1, 5, or 15", p.LoadAverage)
+	}
+	if p.ProcRoot == "" {
+		p.ProcRoot = DefaultProcRoot
+	}
+	return p, nil
+}
+
+func (p *SystemChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "systemchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	if p.MaxLoadPerCPU > 0 {
+		loads, err := readLoadAvg(p.ProcRoot)
+		if err != nil {
+			return fmt.Errorf("read loadavg failed: %w", err)
+		}
+		ncpu, err := countCPUs(p.ProcRoot)
+		if err != nil {
+			return fmt.Errorf("read stat failed: %w", err)
+		}
+		load := loads[p.LoadAverage]
+		perCPU := load / float64(ncpu)
+		logger.Debug(fmt.Sprintf("load average (%dm) %.2f, %d cpus, %.2f per cpu", p.LoadAverage, load, ncpu, perCPU))
+		if perCPU > p.MaxLoadPerCPU {
+			return fmt.Errorf("load average (%dm) per cpu %.2f exceeds max_load_per_cpu %.2f", p.LoadAverage, perCPU, p.MaxLoadPerCPU)
+		}
+	}
+
+	if p.MinMemAvailable != nil || p.MaxSwapUsed != nil {
+		mem, err := readMemInfo(p.ProcRoot)
+		if err != nil {
+			return fmt.Errorf("read meminfo failed: %w", err)
+		}
+		total, avail := mem["MemTotal"], mem["MemAvailable"]
+		swapTotal := mem["SwapTotal"]
+		swapUsed := swapTotal - min(mem["SwapFree"], swapTotal)
+		logger.Debug(fmt.Sprintf("memory available %d/%d bytes (%.1f%%), swap used %d/%d bytes (%.1f%%)",
+			avail, total, percentOf(avail, total), swapUsed, swapTotal, percentOf(swapUsed, swapTotal)))
+		if p.MinMemAvailable != nil && p.MinMemAvailable.below(avail, total) {
+			return fmt.Errorf("memory available %d bytes (%.1f%%) is below min_mem_available %s", avail, percentOf(avail, total), p.MinMemAvailable)
+		}
+		if p.MaxSwapUsed != nil && p.MaxSwapUsed.above(swapUsed, swapTotal) {
+			return fmt.Errorf("swap used %d bytes (%.1f%%) exceeds max_swap_used %s", swapUsed, percentOf(swapUsed, swapTotal), p.MaxSwapUsed)
+		}
+	}
+
+	if p.MaxCPUSteal > 0 {
+		steal, err := p.sampleCPUSteal(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Debug(fmt.Sprintf("cpu steal %.1f%%", steal))
+		if steal > p.MaxCPUSteal {
+			return fmt.Errorf("cpu steal %.1f%% exceeds max_cpu_steal %.1f%%", steal, p.MaxCPUSteal)
+		}
+	}
+	return nil
+}
+
+// sampleCPUSteal returns the percentage of steal time of all CPUs in a sample duration.
+func (p *SystemChecker) sampleCPUSteal(ctx context.Context) (float64, error) {
+	before, err := readCPUTimes(p.ProcRoot)
+	if err != nil {
+		return 0, fmt.Errorf("read stat failed: %w", err)
+	}
+	select {
+	case <-time.After(min(DefaultSystemCPUSampleDuration, p.Timeout/2)):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	after, err := readCPUTimes(p.ProcRoot)
+	if err != nil {
+		return 0, fmt.Errorf("read stat failed: %w", err)
+	}
+	return cpuStealPercent(before, after), nil
+}
+
+// cpuStealPercent returns the percentage of steal time between two samples of readCPUTimes.
+func cpuStealPercent(before, after []uint64) float64 {
+	// guest and guest_nice (after steal) are already counted in user and nice.
+	var total, steal uint64
+	for i := 0; i < len(after) && i < len(before) && i < 8; i++ {
+		if after[i] > before[i] {
+			total += after[i] - before[i]
+		}
+	}
+	if len(after) > 7 && len(before) > 7 && after[7] > before[7] {
+		steal = after[7] - before[7]
+	}
+	return percentOf(steal, total)
+}
+
+// readLoadAvg returns the load averages in /proc/loadavg keyed by minutes (1, 5, 15).
+func readLoadAvg(root string) (map[int]float64, error) {
+	b, err := os.ReadFile(filepath.Join(root, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid loadavg: %q", b)
+	}
+	loads := make(map[int]float64, 3)
+	for i, m := range []int{1, 5, 15} {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loadavg: %q", b)
+		}
+		loads[m] = v
+	}
+	return loads, nil
+}
+
+// readMemInfo returns the values in /proc/meminfo in bytes.
+func readMemInfo(root string) (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(root, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mem := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// e.g. "MemAvailable:    1234567 kB"
+		key, value, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			n *= 1024
+		}
+		mem[key] = n
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := mem["MemAvailable"]; !ok {
+		return nil, errors.New("MemAvailable is not found (requires Linux 3.14+)")
+	}
+	return mem, nil
+}
+
+// readCPUTimes returns the aggregated "cpu" line in /proc/stat.
+// user, nice, system, idle, iowait, irq, softirq, steal, guest, guest_nice.
+func readCPUTimes(root string) ([]uint64, error) {
+	b, err := os.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		times := make([]uint64, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			n, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stat: %s", line)
+			}
+			times = append(times, n)
+		}
+		return times, nil
+	}
+	return nil, errors.New("cpu line is not found in stat")
+}
+
+// countCPUs returns the number of "cpuN" lines in /proc/stat.
+func countCPUs(root string) (int, error) {
+	b, err := os.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("cpu")) && len(line) > 3 && line[3] >= '0' && line[3] <= '9' {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, errors.New("no cpu found in stat")
+	}
+	return n, nil
+}
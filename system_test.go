@@ -0,0 +1,79 @@
+package greenlight_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+func TestSystemChecker(t *testing.T) {
+	// testdata/proc: 4 cpus, load average 3.00 2.00 1.00,
+	// 1GiB of 8GiB memory available, 1GiB of 2GiB swap used.
+	tests := []struct {
+		name      string
+		cfg       greenlight.SystemCheckConfig
+		expectErr bool
+	}{
+		{"load", greenlight.SystemCheckConfig{MaxLoadPerCPU: 1.0}, false},
+		{"load exceeded", greenlight.SystemCheckConfig{MaxLoadPerCPU: 0.5}, true},
+		{"load 15m", greenlight.SystemCheckConfig{MaxLoadPerCPU: 0.5, LoadAverage: 15}, false},
+		{"mem available", greenlight.SystemCheckConfig{MinMemAvailable: "512MiB"}, false},
+		{"mem available below", greenlight.SystemCheckConfig{MinMemAvailable: "2GiB"}, true},
+		{"mem available percent", greenlight.SystemCheckConfig{MinMemAvailable: "10%"}, false},
+		{"mem available percent below", greenlight.SystemCheckConfig{MinMemAvailable: "20%"}, true},
+		{"swap used", greenlight.SystemCheckConfig{MaxSwapUsed: "60%"}, false},
+		{"swap used exceeded", greenlight.SystemCheckConfig{MaxSwapUsed: "512MiB"}, true},
+		// the sampling path only, as the static fixture does not change between the samples.
+		{"cpu steal sampled", greenlight.SystemCheckConfig{MaxCPUSteal: 10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ProcRoot = "testdata/proc"
+			checker, err := greenlight.NewSystemChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: 100 * time.Millisecond,
+				System:  &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCPUStealPercent(t *testing.T) {
+	// testdata/proc-next is a sample of testdata/proc later:
+	// 400 ticks elapsed in total, 60 ticks of them are stolen.
+	read := func(root string) []uint64 {
+		t.Helper()
+		times, err := greenlight.ReadCPUTimes(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return times
+	}
+	proc, next := read("testdata/proc"), read("testdata/proc-next")
+	tests := []struct {
+		name          string
+		before, after []uint64
+		expect        float64
+	}{
+		{"stolen", proc, next, 15},
+		{"unchanged", proc, proc, 0},
+		{"counters reset", next, proc, 0},
+		{"no steal field", proc[:4], next[:4], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := greenlight.CPUStealPercent(tt.before, tt.after); got != tt.expect {
+				t.Errorf("expected %v%%, got %v%%", tt.expect, got)
+			}
+		})
+	}
+}
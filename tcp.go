@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -24,6 +26,7 @@ type TCPCheckConfig struct {
 	ExpectPattern      string `yaml:"expect_pattern"`
 	TLS                bool   `yaml:"tls"`
 	NoCheckCertificate bool   `yaml:"no_check_certificate"`
+	ServerName         string `yaml:"server_name"`
 }
 
 type TCPChecker struct {
@@ -36,6 +39,7 @@ type TCPChecker struct {
 	Timeout            time.Duration
 	TLS                bool
 	NoCheckCertificate bool
+	ServerName         string
 
 	name string
 }
@@ -54,6 +58,10 @@ func NewTCPChecker(cfg *CheckConfig) (*TCPChecker, error) {
 		Host:               cfg.TCP.Host,
 		Port:               cfg.TCP.Port,
 		Send:               cfg.TCP.Send,
+		ServerName:         cfg.TCP.ServerName,
+	}
+	if isUnixAddr(p.Host) && p.TLS && !p.NoCheckCertificate && p.ServerName == "" {
+		return nil, errors.New("tcp server_name is required for tls over a unix socket")
 	}
 	if cfg.TCP.ExpectPattern != "" {
 		pt, err := regexp.Compile(cfg.TCP.ExpectPattern)
@@ -73,8 +81,8 @@ func (p *TCPChecker) Run(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
 	defer cancel()
 
-	addr := net.JoinHostPort(p.Host, p.Port)
-	conn, err := dialTCP(ctx, addr, p.TLS, p.NoCheckCertificate, p.Timeout)
+	addr := dialAddr(p.Host, p.Port)
+	conn, err := dialTCP(ctx, addr, p.TLS, p.NoCheckCertificate, p.ServerName, p.Timeout)
 	if err != nil {
 		return fmt.Errorf("tcp connect failed: %w", err)
 	}
@@ -109,16 +117,46 @@ func (p *TCPChecker) Run(ctx context.Context) error {
 	return nil
 }
 
-func dialTCP(ctx context.Context, address string, useTLS bool, noCheckCertificate bool, timeout time.Duration) (net.Conn, error) {
+// isUnixAddr reports whether the host is a Unix domain socket like "unix:/path/to/socket".
+func isUnixAddr(host string) bool {
+	return strings.HasPrefix(host, "unix:")
+}
+
+// dialAddr returns the address for dialTCP. The port is ignored for a Unix domain socket.
+func dialAddr(host, port string) string {
+	if isUnixAddr(host) {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// dialTCP connects to the address. "unix:/path/to/socket" connects to the Unix domain socket.
+// The server name for TLS defaults to the host of the address, but never to the path of a Unix domain socket.
+func dialTCP(ctx context.Context, address string, useTLS bool, noCheckCertificate bool, serverName string, timeout time.Duration) (net.Conn, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		network, address = "unix", path
+	}
 	d := &net.Dialer{Timeout: timeout}
-	if useTLS {
-		td := &tls.Dialer{
-			NetDialer: d,
-			Config: &tls.Config{
-				InsecureSkipVerify: noCheckCertificate,
-			},
-		}
-		return td.DialContext(ctx, "tcp", address)
+	if !useTLS {
+		return d.DialContext(ctx, network, address)
+	}
+	config := &tls.Config{
+		InsecureSkipVerify: noCheckCertificate,
+		ServerName:         serverName,
+	}
+	if network == "tcp" {
+		td := &tls.Dialer{NetDialer: d, Config: config}
+		return td.DialContext(ctx, network, address)
+	}
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(conn, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
 	}
-	return d.DialContext(ctx, "tcp", address)
+	return tc, nil
 }
@@ -0,0 +1,158 @@
+package greenlight_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// unixListen listens on a Unix domain socket in a short temporary path, as the length of a socket path is limited.
+func unixListen(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "greenlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "s.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, path
+}
+
+// serveUnix serves the connections on a Unix domain socket, and returns the address as "unix:/path/to/socket".
+func serveUnix(t *testing.T, serve func(net.Conn) error) string {
+	t.Helper()
+	l, path := unixListen(t)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := serve(conn); err != nil && err != io.EOF {
+					t.Log("fake server:", err)
+				}
+			}()
+		}
+	}()
+	return "unix:" + path
+}
+
+func TestTCPCheckerUnixSocket(t *testing.T) {
+	_, certPEM, keyPEM := newTestCertificate(t, 60)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serverName atomic.Value
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName.Store(hello.ServerName)
+			return nil, nil
+		},
+	}
+	plain := serveUnix(t, func(conn net.Conn) error {
+		_, err := io.WriteString(conn, "+OK ready\r\n")
+		return err
+	})
+	secure := serveUnix(t, func(conn net.Conn) error {
+		tc := tls.Server(conn, serverTLS)
+		_, err := io.WriteString(tc, "+OK ready\r\n")
+		return err
+	})
+
+	tests := []struct {
+		name             string
+		cfg              greenlight.TCPCheckConfig
+		expectNewErr     bool
+		expectErr        bool
+		expectServerName string
+	}{
+		{"plain", greenlight.TCPCheckConfig{Host: plain, ExpectPattern: "^\\+OK"}, false, false, ""},
+		{"plain port ignored", greenlight.TCPCheckConfig{Host: plain, Port: "6379", ExpectPattern: "^\\+OK"}, false, false, ""},
+		{"tls without server_name", greenlight.TCPCheckConfig{Host: secure, TLS: true}, true, false, ""},
+		{"tls no_check_certificate", greenlight.TCPCheckConfig{Host: secure, TLS: true, NoCheckCertificate: true, ExpectPattern: "^\\+OK"}, false, false, ""},
+		{"tls server_name", greenlight.TCPCheckConfig{Host: secure, TLS: true, NoCheckCertificate: true, ServerName: "app.example.com", ExpectPattern: "^\\+OK"}, false, false, "app.example.com"},
+		{"tls unknown authority", greenlight.TCPCheckConfig{Host: secure, TLS: true, ServerName: "app.example.com"}, false, true, "app.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverName.Store("")
+			checker, err := greenlight.NewTCPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				TCP:     &tt.cfg,
+			})
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("expected new error: %v, got: %v", tt.expectNewErr, err)
+			}
+			if err != nil {
+				return
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if got := serverName.Load(); got != tt.expectServerName {
+				t.Errorf("expected server name %q, got %q", tt.expectServerName, got)
+			}
+		})
+	}
+}
+
+func TestCheckersUnixSocket(t *testing.T) {
+	redis := &fakeRedis{}
+	postgres := &fakePostgres{auth: "trust", user: "app"}
+	mysql := &fakeMySQL{user: "app", plugin: "mysql_native_password"}
+	redisAddr := serveUnix(t, redis.serve)
+	postgresAddr := serveUnix(t, postgres.serve)
+	mysqlAddr := serveUnix(t, mysql.serve)
+	mysqlFullAuth := &fakeMySQL{user: "app", password: "secret", plugin: "caching_sha2_password", fullAuth: true}
+	mysqlFullAuthAddr := serveUnix(t, mysqlFullAuth.serve)
+	t.Setenv("MYSQL_PWD", "secret")
+
+	tests := []struct {
+		name         string
+		cfg          greenlight.CheckConfig
+		expectNewErr bool
+	}{
+		{"redis", greenlight.CheckConfig{Redis: &greenlight.RedisCheckConfig{Host: redisAddr}}, false},
+		{"redis tls", greenlight.CheckConfig{Redis: &greenlight.RedisCheckConfig{Host: redisAddr, TLS: true}}, true},
+		{"postgres", greenlight.CheckConfig{Postgres: &greenlight.PostgresCheckConfig{Host: postgresAddr, User: "app"}}, false},
+		{"postgres sslmode disable", greenlight.CheckConfig{Postgres: &greenlight.PostgresCheckConfig{Host: postgresAddr, User: "app", SSLMode: "disable"}}, false},
+		{"postgres sslmode require", greenlight.CheckConfig{Postgres: &greenlight.PostgresCheckConfig{Host: postgresAddr, User: "app", SSLMode: "require"}}, true},
+		{"mysql", greenlight.CheckConfig{MySQL: &greenlight.MySQLCheckConfig{Host: mysqlAddr, User: "app"}}, false},
+		{"mysql full auth in clear text", greenlight.CheckConfig{MySQL: &greenlight.MySQLCheckConfig{Host: mysqlFullAuthAddr, User: "app", PasswordEnv: "MYSQL_PWD"}}, false},
+		{"mysql tls", greenlight.CheckConfig{MySQL: &greenlight.MySQLCheckConfig{Host: mysqlAddr, User: "app", TLS: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name, tt.cfg.Timeout = tt.name, time.Second
+			checker, err := greenlight.NewChecker(&tt.cfg)
+			if (err != nil) != tt.expectNewErr {
+				t.Fatalf("expected new error: %v, got: %v", tt.expectNewErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if err := checker.Run(context.Background()); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
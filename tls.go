@@ -0,0 +1,207 @@
+package greenlight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+type TLSCheckConfig struct {
+	Host          string   `yaml:"host"`
+	Port          string   `yaml:"port"`
+	ServerName    string   `yaml:"server_name"`
+	File          string   `yaml:"file"`
+	CAFile        string   `yaml:"ca_file"`
+	NoVerifyChain bool     `yaml:"no_verify_chain"`
+	ExpectNames   []string `yaml:"expect_names"`
+	MinDays       int      `yaml:"min_days"`
+	WarnDays      int      `yaml:"warn_days"`
+}
+
+type TLSChecker struct {
+	Host          string
+	Port          string
+	ServerName    string
+	File          string
+	RootCAs       *x509.CertPool // nil means the system roots
+	NoVerifyChain bool
+	ExpectNames   []string
+	MinDays       int
+	WarnDays      int
+	Timeout       time.Duration
+
+	name string
+}
+
+func (p *TLSChecker) Name() string {
+	return p.name
+}
+
+func NewTLSChecker(cfg *CheckConfig) (*TLSChecker, error) {
+	c := cfg.TLS
+	p := &TLSChecker{
+		name:          cfg.Name,
+		Timeout:       cfg.Timeout,
+		Host:          c.Host,
+		Port:          c.Port,
+		ServerName:    c.ServerName,
+		File:          c.File,
+		NoVerifyChain: c.NoVerifyChain,
+		ExpectNames:   c.ExpectNames,
+		MinDays:       c.MinDays,
+		WarnDays:      c.WarnDays,
+	}
+	switch {
+	case p.File != "" && p.Host != "":
+		return nil, errors.New("tls host and file are exclusive")
+	case p.File == "" && p.Host == "":
+		return nil, errors.New("tls host or file is required")
+	}
+	if c.CAFile != "" {
+		var err error
+		if p.RootCAs, err = readCertPool(c.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	// default
+	if p.Host != "" {
+		if p.Port == "" {
+			p.Port = "443"
+		}
+		if p.ServerName == "" {
+			p.ServerName = p.Host
+		}
+		if len(p.ExpectNames) == 0 && net.ParseIP(p.ServerName) == nil {
+			p.ExpectNames = []string{p.ServerName}
+		}
+	}
+	return p, nil
+}
+
+func (p *TLSChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "tlschecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	var certs []*x509.Certificate
+	var err error
+	if p.File != "" {
+		certs, err = readCertificates(p.File)
+	} else {
+		certs, err = p.peerCertificates(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	leaf := certs[0]
+	logger.Debug(fmt.Sprintf("certificate subject=%s issuer=%s not_after=%s dns_names=%s",
+		leaf.Subject, leaf.Issuer, leaf.NotAfter.Format(time.RFC3339), strings.Join(leaf.DNSNames, ",")))
+
+	// the earliest expiry in the chain.
+	now := time.Now()
+	expiry := leaf
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expiry.NotAfter) {
+			expiry = cert
+		}
+	}
+	if now.After(expiry.NotAfter) {
+		return fmt.Errorf("certificate %s has expired at %s", expiry.Subject, expiry.NotAfter.Format(time.RFC3339))
+	}
+	days := int(expiry.NotAfter.Sub(now).Hours() / 24)
+	logger.Debug(fmt.Sprintf("certificate %s expires in %d days", expiry.Subject, days))
+	if days < p.MinDays {
+		return fmt.Errorf("certificate %s expires in %d days at %s, less than min_days %d", expiry.Subject, days, expiry.NotAfter.Format(time.RFC3339), p.MinDays)
+	}
+
+	if !p.NoVerifyChain {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		opts := x509.VerifyOptions{Roots: p.RootCAs, Intermediates: intermediates, CurrentTime: now}
+		if _, err := leaf.Verify(opts); err != nil {
+			return fmt.Errorf("certificate verify failed: %w", err)
+		}
+	}
+	for _, name := range p.ExpectNames {
+		if err := leaf.VerifyHostname(name); err != nil {
+			return fmt.Errorf("certificate does not match %s: %w", name, err)
+		}
+	}
+
+	if days < p.WarnDays {
+		return newWarning("certificate %s expires in %d days at %s, less than warn_days %d", expiry.Subject, days, expiry.NotAfter.Format(time.RFC3339), p.WarnDays)
+	}
+	return nil
+}
+
+// peerCertificates returns the certificates presented by the server.
+// The verification is done by Run, to report expiry and names even if the chain is invalid.
+func (p *TLSChecker) peerCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	d := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: p.Timeout},
+		Config: &tls.Config{
+			ServerName:         p.ServerName,
+			InsecureSkipVerify: true,
+		},
+	}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(p.Host, p.Port))
+	if err != nil {
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate presented by the server")
+	}
+	return certs, nil
+}
+
+// readCertPool reads a PEM bundle of CA certificates.
+func readCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read ca_file failed: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate found in ca_file %s", file)
+	}
+	return pool, nil
+}
+
+// readCertificates reads a PEM file. The first certificate is the leaf.
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read certificate file failed: %w", err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate failed: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return certs, nil
+}
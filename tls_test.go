@@ -0,0 +1,137 @@
+package greenlight_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// newTestCertificate issues a certificate for app.example.com valid for days, by a new CA.
+func newTestCertificate(t *testing.T, days int) (caPEM, certPEM, keyPEM []byte) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		DNSNames:     []string{"app.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSChecker(t *testing.T) {
+	caPEM, certPEM, keyPEM := newTestCertificate(t, 60)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	os.WriteFile(caFile, caPEM, 0644)
+	os.WriteFile(certFile, certPEM, 0644)
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{pair}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+
+	tests := []struct {
+		name       string
+		cfg        greenlight.TLSCheckConfig
+		expectErr  bool
+		expectWarn bool
+	}{
+		{"file", greenlight.TLSCheckConfig{File: certFile, CAFile: caFile, MinDays: 30}, false, false},
+		{"file warn_days", greenlight.TLSCheckConfig{File: certFile, CAFile: caFile, MinDays: 30, WarnDays: 90}, true, true},
+		{"file min_days", greenlight.TLSCheckConfig{File: certFile, CAFile: caFile, MinDays: 90}, true, false},
+		{"file untrusted", greenlight.TLSCheckConfig{File: certFile}, true, false},
+		{"file no_verify_chain", greenlight.TLSCheckConfig{File: certFile, NoVerifyChain: true}, false, false},
+		{"file expect_names", greenlight.TLSCheckConfig{File: certFile, CAFile: caFile, ExpectNames: []string{"app.example.com"}}, false, false},
+		{"file expect_names mismatch", greenlight.TLSCheckConfig{File: certFile, CAFile: caFile, ExpectNames: []string{"db.example.com"}}, true, false},
+		{"handshake", greenlight.TLSCheckConfig{Host: host, Port: port, ServerName: "app.example.com", CAFile: caFile}, false, false},
+		{"handshake wrong server_name", greenlight.TLSCheckConfig{Host: host, Port: port, ServerName: "db.example.com", CAFile: caFile}, true, false},
+		{"handshake warn_days", greenlight.TLSCheckConfig{Host: host, Port: port, ServerName: "app.example.com", CAFile: caFile, WarnDays: 90}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewTLSChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: time.Second,
+				TLS:     &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			var w *greenlight.WarningError
+			if errors.As(err, &w) != tt.expectWarn {
+				t.Errorf("unexpected warning: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package greenlight
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultUDPMaxBytes = 64 * 1024
+	DefaultUDPRetries  = 2
+)
+
+type UDPCheckConfig struct {
+	Host          string `yaml:"host"`
+	Port          string `yaml:"port"`
+	Send          string `yaml:"send"`
+	SendHex       string `yaml:"send_hex"`
+	MaxBytes      int    `yaml:"max_bytes"`
+	ExpectPattern string `yaml:"expect_pattern"`
+	Retries       *int   `yaml:"retries"`
+}
+
+type UDPChecker struct {
+	Host          string
+	Port          string
+	Payload       []byte
+	MaxBytes      int
+	ExpectPattern *regexp.Regexp
+	Retries       int
+	Timeout       time.Duration
+
+	name string
+}
+
+func (p *UDPChecker) Name() string {
+	return p.name
+}
+
+func NewUDPChecker(cfg *CheckConfig) (*UDPChecker, error) {
+	p := &UDPChecker{
+		name:     cfg.Name,
+		Timeout:  cfg.Timeout,
+		Host:     cfg.UDP.Host,
+		Port:     cfg.UDP.Port,
+		MaxBytes: cfg.UDP.MaxBytes,
+		Retries:  DefaultUDPRetries,
+	}
+	switch {
+	case cfg.UDP.Send != "" && cfg.UDP.SendHex != "":
+		return nil, errors.New("udp send and send_hex are exclusive")
+	case cfg.UDP.SendHex != "":
+		b, err := hex.DecodeString(strings.Join(strings.Fields(cfg.UDP.SendHex), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid send_hex: %w", err)
+		}
+		p.Payload = b
+	case cfg.UDP.Send != "":
+		p.Payload = []byte(cfg.UDP.Send)
+	default:
+		return nil, errors.New("udp send or send_hex is required")
+	}
+	if cfg.UDP.ExpectPattern != "" {
+		pt, err := regexp.Compile(cfg.UDP.ExpectPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+		p.ExpectPattern = pt
+	}
+	if cfg.UDP.Retries != nil {
+		if *cfg.UDP.Retries < 0 {
+			return nil, fmt.Errorf("udp retries must not be negative: %d", *cfg.UDP.Retries)
+		}
+		p.Retries = *cfg.UDP.Retries
+	}
+	if p.MaxBytes == 0 {
+		p.MaxBytes = DefaultUDPMaxBytes
+	}
+	return p, nil
+}
+
+func (p *UDPChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "udpchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(p.Host, p.Port)
+	d := &net.Dialer{Timeout: p.Timeout}
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return fmt.Errorf("udp dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	// The timeout is divided by the number of attempts.
+	attempts := p.Retries + 1
+	wait := p.Timeout / time.Duration(attempts)
+	buf := make([]byte, p.MaxBytes)
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		logger.Debug(fmt.Sprintf("send %d bytes to %s", len(p.Payload), addr), "attempt", i+1)
+		if _, err := conn.Write(p.Payload); err != nil {
+			return fmt.Errorf("udp send failed: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(wait))
+		var readErr error
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				readErr = err
+				break
+			}
+			logger.Debug("read " + string(buf[:n]))
+			if p.ExpectPattern == nil || p.ExpectPattern.Match(buf[:n]) {
+				return nil
+			}
+			lastErr = fmt.Errorf("udp unexpected response: %s", string(buf[:n]))
+			// continue to read. a late response for the previous attempt may arrive.
+		}
+		if !errors.Is(readErr, os.ErrDeadlineExceeded) {
+			lastErr = readErr
+			break // e.g. connection refused by ICMP port unreachable
+		}
+		if lastErr == nil {
+			lastErr = readErr
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("udp no expected response in %d attempts: %w", attempts, lastErr)
+}
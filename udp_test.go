@@ -0,0 +1,179 @@
+package greenlight_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// fakeUDP is a local UDP server. reply returns the responses for the n-th datagram (0-origin).
+type fakeUDP struct {
+	reply    func(n int, payload []byte) []string
+	delay    time.Duration // delays the responses to the first datagram
+	received atomic.Int32
+}
+
+func (s *fakeUDP) run(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			i := int(s.received.Add(1)) - 1
+			responses := s.reply(i, buf[:n])
+			go func() {
+				if i == 0 {
+					time.Sleep(s.delay)
+				}
+				for _, r := range responses {
+					pc.WriteTo([]byte(r), addr)
+				}
+			}()
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestUDPChecker(t *testing.T) {
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.LocalAddr().String()
+	closed.Close()
+
+	echo := func(n int, payload []byte) []string { return []string{"OK " + string(payload)} }
+	retries := func(n int) *int { return &n }
+	tests := []struct {
+		name          string
+		server        *fakeUDP
+		cfg           greenlight.UDPCheckConfig
+		expectErr     string
+		expectAttempt int32
+	}{
+		{
+			name:          "reply",
+			server:        &fakeUDP{reply: echo},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", ExpectPattern: "^OK ping$"},
+			expectAttempt: 1,
+		},
+		{
+			name: "first datagram dropped",
+			server: &fakeUDP{reply: func(n int, payload []byte) []string {
+				if n == 0 {
+					return nil
+				}
+				return echo(n, payload)
+			}},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", ExpectPattern: "^OK"},
+			expectAttempt: 2,
+		},
+		{
+			name:          "all datagrams dropped",
+			server:        &fakeUDP{reply: func(int, []byte) []string { return nil }},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", Retries: retries(1)},
+			expectErr:     "in 2 attempts",
+			expectAttempt: 2,
+		},
+		{
+			name:          "non-matching payload",
+			server:        &fakeUDP{reply: func(int, []byte) []string { return []string{"NG"} }},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", ExpectPattern: "^OK"},
+			expectErr:     "udp unexpected response: NG",
+			expectAttempt: 3,
+		},
+		{
+			name:          "non-matching then matching",
+			server:        &fakeUDP{reply: func(int, []byte) []string { return []string{"NG", "OK"} }},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", ExpectPattern: "^OK"},
+			expectAttempt: 1,
+		},
+		{
+			name: "late response to the first datagram",
+			server: &fakeUDP{delay: 300 * time.Millisecond, reply: func(n int, payload []byte) []string {
+				if n == 0 {
+					return echo(n, payload)
+				}
+				return nil
+			}},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", ExpectPattern: "^OK"},
+			expectAttempt: 2,
+		},
+		{
+			name:          "no retries",
+			server:        &fakeUDP{reply: func(int, []byte) []string { return nil }},
+			cfg:           greenlight.UDPCheckConfig{Send: "ping", Retries: retries(0)},
+			expectErr:     "in 1 attempts",
+			expectAttempt: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _ := net.SplitHostPort(tt.server.run(t))
+			tt.cfg.Host, tt.cfg.Port = host, port
+			checker, err := greenlight.NewUDPChecker(&greenlight.CheckConfig{
+				Name:    tt.name,
+				Timeout: 750 * time.Millisecond, // 250ms per attempt by default
+				UDP:     &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if tt.expectErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.expectErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectErr)) {
+				t.Errorf("expected error %q, got: %v", tt.expectErr, err)
+			}
+			if got := tt.server.received.Load(); got != tt.expectAttempt {
+				t.Errorf("expected %d datagrams, got %d", tt.expectAttempt, got)
+			}
+		})
+	}
+
+	t.Run("port unreachable", func(t *testing.T) {
+		host, port, _ := net.SplitHostPort(closedAddr)
+		checker, err := greenlight.NewUDPChecker(&greenlight.CheckConfig{
+			Name:    "port unreachable",
+			Timeout: 5 * time.Second,
+			UDP:     &greenlight.UDPCheckConfig{Host: host, Port: port, Send: "ping"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if err := checker.Run(context.Background()); err == nil {
+			t.Error("expected error")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("refused port should fail without retries, took %s", elapsed)
+		}
+	})
+}
+
+func TestNewUDPCheckerInvalidRetries(t *testing.T) {
+	for _, n := range []int{-1, -2} {
+		_, err := greenlight.NewUDPChecker(&greenlight.CheckConfig{
+			Timeout: time.Second,
+			UDP:     &greenlight.UDPCheckConfig{Host: "127.0.0.1", Port: "53", Send: "ping", Retries: &n},
+		})
+		if err == nil {
+			t.Errorf("expected error for retries %d", n)
+		} else if !strings.Contains(err.Error(), "must not be negative") {
+			t.Errorf("unexpected error for retries %d: %s", n, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package greenlight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var byteSizeUnits = []struct {
+	suffix string
+	n      int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ByteSize is a size in bytes. It can be written with a unit like "512MiB" or "1GB" in YAML.
+type ByteSize int64
+
+func (s *ByteSize) UnmarshalYAML(b []byte) error {
+	n, err := parseByteSize(strings.Trim(strings.TrimSpace(string(b)), `"'`))
+	if err != nil {
+		return err
+	}
+	*s = ByteSize(n)
+	return nil
+}
+
+func parseByteSize(s string) (int64, error) {
+	num := s
+	mul := int64(1)
+	for _, u := range byteSizeUnits {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			num, mul = strings.TrimSpace(v), u.n
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return int64(f * float64(mul)), nil
+}
+
+// SizeThreshold is an absolute value like "1GiB" (or "10000" for counts), or a percentage like "10%".
+type SizeThreshold string
+
+func (v *SizeThreshold) UnmarshalYAML(b []byte) error {
+	*v = SizeThreshold(strings.Trim(strings.TrimSpace(string(b)), `"'`))
+	return nil
+}
+
+// sizeLimit is a parsed SizeThreshold.
+type sizeLimit struct {
+	Value   float64
+	Percent bool
+}
+
+func (l *sizeLimit) String() string {
+	return formatThreshold(l.Value, l.Percent)
+}
+
+// below reports whether v is below the limit.
+func (l *sizeLimit) below(v, total uint64) bool {
+	if l.Percent {
+		return percentOf(v, total) < l.Value
+	}
+	return float64(v) < l.Value
+}
+
+// above reports whether v is above the limit.
+func (l *sizeLimit) above(v, total uint64) bool {
+	if l.Percent {
+		return percentOf(v, total) > l.Value
+	}
+	return float64(v) > l.Value
+}
+
+// parseSizeThreshold parses v as bytes with a unit, or a plain number if bytes is false.
+func parseSizeThreshold(v SizeThreshold, bytes bool) (*sizeLimit, error) {
+	s := strings.TrimSpace(string(v))
+	if s == "" {
+		return nil, nil
+	}
+	f, percent, err := parseThreshold(s, bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &sizeLimit{Value: f, Percent: percent}, nil
+}
+
+// parseThreshold parses s as a percentage like "10%", bytes with a unit, or a plain number if bytes is false.
+// It is shared by DiskThreshold and SizeThreshold.
+func parseThreshold(s string, bytes bool) (float64, bool, error) {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || f < 0 || f > 100 {
+			return 0, false, fmt.Errorf("invalid percentage %q", s)
+		}
+		return f, true, nil
+	}
+	if bytes {
+		n, err := parseByteSize(s)
+		if err != nil {
+			return 0, false, err
+		}
+		return float64(n), false, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid number %q", s)
+	}
+	return float64(n), false, nil
+}
+
+func formatThreshold(v float64, percent bool) string {
+	if percent {
+		return fmt.Sprintf("%.1f%%", v)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func percentOf(v, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) / float64(total) * 100
+}
@@ -0,0 +1,330 @@
+package greenlight
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	DefaultWebSocketMaxBytes = 64 * 1024
+)
+
+// websocketGUID is the magic string to compute Sec-WebSocket-Accept. (RFC 6455)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xa
+)
+
+type WebSocketCheckConfig struct {
+	URL                string            `yaml:"url"`
+	Headers            map[string]string `yaml:"headers"`
+	Subprotocol        string            `yaml:"subprotocol"`
+	Send               string            `yaml:"send"`
+	ExpectPattern      string            `yaml:"expect_pattern"`
+	MaxBytes           int               `yaml:"max_bytes"`
+	NoCheckCertificate bool              `yaml:"no_check_certificate"`
+}
+
+type WebSocketChecker struct {
+	URL                *url.URL
+	Headers            map[string]string
+	Subprotocol        string
+	Send               string
+	ExpectPattern      *regexp.Regexp
+	MaxBytes           int
+	NoCheckCertificate bool
+	Timeout            time.Duration
+
+	name string
+}
+
+func (p *WebSocketChecker) Name() string {
+	return p.name
+}
+
+func NewWebSocketChecker(cfg *CheckConfig) (*WebSocketChecker, error) {
+	c := cfg.WebSocket
+	p := &WebSocketChecker{
+		name:               cfg.Name,
+		Timeout:            cfg.Timeout,
+		Headers:            c.Headers,
+		Subprotocol:        c.Subprotocol,
+		Send:               c.Send,
+		MaxBytes:           c.MaxBytes,
+		NoCheckCertificate: c.NoCheckCertificate,
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", c.URL, err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("invalid url %s: scheme must be ws or wss", c.URL)
+	}
+	p.URL = u
+	if c.ExpectPattern != "" {
+		if c.Send == "" {
+			return nil, errors.New("websocket expect_pattern requires send")
+		}
+		if p.ExpectPattern, err = regexp.Compile(c.ExpectPattern); err != nil {
+			return nil, fmt.Errorf("invalid expect_pattern: %w", err)
+		}
+	}
+	// default
+	if p.MaxBytes == 0 {
+		p.MaxBytes = DefaultWebSocketMaxBytes
+	}
+	return p, nil
+}
+
+func (p *WebSocketChecker) Run(ctx context.Context) error {
+	logger := newLoggerFromContext(ctx).With("name", p.name, "module", "websocketchecker")
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	addr := p.URL.Host
+	if p.URL.Port() == "" {
+		port := "80"
+		if p.URL.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(p.URL.Hostname(), port)
+	}
+	conn, err := dialTCP(ctx, addr, p.URL.Scheme == "wss", p.NoCheckCertificate, "", p.Timeout)
+	if err != nil {
+		return fmt.Errorf("websocket dial failed: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	key, err := newWebSocketKey()
+	if err != nil {
+		return err
+	}
+	u := *p.URL
+	u.Scheme = "http"
+	if p.URL.Scheme == "wss" {
+		u.Scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("websocket request failed: %w", err)
+	}
+	for k, v := range p.Headers {
+		if k == "Host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if p.Subprotocol != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", p.Subprotocol)
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("websocket request failed: %w", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return fmt.Errorf("websocket read response failed: %w", err)
+	}
+	resp.Body.Close()
+	logger.Debug(fmt.Sprintf("status %s", resp.Status))
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("websocket unexpected status %s", resp.Status)
+	}
+	if got, expect := resp.Header.Get("Sec-WebSocket-Accept"), websocketAccept(key); got != expect {
+		return fmt.Errorf("websocket invalid Sec-WebSocket-Accept %q, expected %q", got, expect)
+	}
+	if p.Subprotocol != "" && resp.Header.Get("Sec-WebSocket-Protocol") != p.Subprotocol {
+		return fmt.Errorf("websocket subprotocol %s is not accepted", p.Subprotocol)
+	}
+
+	if p.Send != "" {
+		if err := writeWebSocketFrame(conn, wsOpText, []byte(p.Send)); err != nil {
+			return fmt.Errorf("websocket send failed: %w", err)
+		}
+		msg, err := p.readMessage(conn, br)
+		if err != nil {
+			return err
+		}
+		logger.Debug("read " + string(msg))
+		if p.ExpectPattern != nil && !p.ExpectPattern.Match(msg) {
+			return fmt.Errorf("websocket unexpected message: %s", string(msg))
+		}
+	}
+
+	// close handshake. the server must respond a close frame in the timeout.
+	payload := binary.BigEndian.AppendUint16(nil, 1000) // normal closure
+	if err := writeWebSocketFrame(conn, wsOpClose, payload); err != nil {
+		return fmt.Errorf("websocket close failed: %w", err)
+	}
+	if err := p.readClose(br); err != nil {
+		return err
+	}
+	logger.Debug("closed")
+	return nil
+}
+
+// readClose reads frames until a close frame from the server, and validates it.
+// Messages and pings sent before the server received the close frame are discarded.
+func (p *WebSocketChecker) readClose(r io.Reader) error {
+	for {
+		fin, op, payload, err := readWebSocketFrame(r, p.MaxBytes)
+		if err != nil {
+			return fmt.Errorf("websocket close response failed: %w", err)
+		}
+		if op != wsOpClose {
+			continue
+		}
+		// a control frame must not be fragmented, and the payload is a status code and an UTF-8 reason.
+		if !fin || len(payload) == 1 || len(payload) > 125 || (len(payload) > 2 && !utf8.Valid(payload[2:])) {
+			return errors.New("websocket invalid close frame from server")
+		}
+		if len(payload) > 0 && binary.BigEndian.Uint16(payload) != 1000 {
+			return fmt.Errorf("websocket closed by server: %s", formatCloseFrame(payload))
+		}
+		return nil
+	}
+}
+
+// readMessage reads a text or binary message. Control frames from the server are handled.
+func (p *WebSocketChecker) readMessage(conn net.Conn, r io.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := readWebSocketFrame(r, p.MaxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("websocket read failed: %w", err)
+		}
+		switch op {
+		case wsOpPing:
+			if err := writeWebSocketFrame(conn, wsOpPong, payload); err != nil {
+				return nil, fmt.Errorf("websocket pong failed: %w", err)
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return nil, fmt.Errorf("websocket closed by server: %s", formatCloseFrame(payload))
+		}
+		msg = append(msg, payload...)
+		if len(msg) > p.MaxBytes {
+			return nil, fmt.Errorf("websocket message exceeds max_bytes %d", p.MaxBytes)
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func newWebSocketKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("websocket key generation failed: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// writeWebSocketFrame writes a single masked frame, as a client must mask all frames.
+func writeWebSocketFrame(w io.Writer, op byte, payload []byte) error {
+	buf := []byte{0x80 | op} // FIN
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, 0x80|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 0x80|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0x80|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	buf = append(buf, mask[:]...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readWebSocketFrame reads a frame from the server.
+func readWebSocketFrame(r io.Reader, maxBytes int) (fin bool, op byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(r, h[:]); err != nil {
+		return
+	}
+	fin, op = h[0]&0x80 != 0, h[0]&0x0f
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > uint64(maxBytes) {
+		err = fmt.Errorf("frame size %d exceeds max_bytes %d", n, maxBytes)
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+func formatCloseFrame(payload []byte) string {
+	if len(payload) < 2 {
+		return "no status"
+	}
+	return fmt.Sprintf("status %d %s", binary.BigEndian.Uint16(payload), string(payload[2:]))
+}
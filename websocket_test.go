@@ -0,0 +1,186 @@
+package greenlight_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/greenlight"
+)
+
+// websocketEchoHandler replies "echo: <message>" to a text frame, and the normal closure to a close frame.
+// Frames from the client must be masked.
+func websocketEchoHandler(w http.ResponseWriter, r *http.Request) {
+	websocketHandler(func(rw *bufio.ReadWriter) {
+		rw.Write([]byte{0x88, 2, 0x03, 0xe8})
+		rw.Flush()
+	})(w, r)
+}
+
+// websocketHandler is websocketEchoHandler which calls onClose for a close frame.
+func websocketHandler(onClose func(rw *bufio.ReadWriter)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+		rw.Flush()
+		for {
+			var head [6]byte // small masked frames only
+			if _, err := io.ReadFull(rw, head[:]); err != nil {
+				return
+			}
+			payload := make([]byte, head[1]&0x7f)
+			io.ReadFull(rw, payload)
+			for i := range payload {
+				payload[i] ^= head[2+i%4]
+			}
+			switch head[0] & 0x0f {
+			case 0x1:
+				msg := "echo: " + string(payload)
+				rw.Write([]byte{0x89, 0}) // ping before the reply
+				rw.Write(append([]byte{0x81, byte(len(msg))}, msg...))
+				rw.Flush()
+			case 0x8:
+				onClose(rw)
+				return
+			}
+		}
+	}
+}
+
+func TestWebSocketChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(websocketEchoHandler))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		name      string
+		cfg       greenlight.WebSocketCheckConfig
+		expectErr bool
+	}{
+		{"handshake", greenlight.WebSocketCheckConfig{URL: wsURL}, false},
+		{"send", greenlight.WebSocketCheckConfig{URL: wsURL, Send: "hello", ExpectPattern: "^echo: hello$"}, false},
+		{"unexpected message", greenlight.WebSocketCheckConfig{URL: wsURL, Send: "hello", ExpectPattern: "^pong$"}, true},
+		{"subprotocol not accepted", greenlight.WebSocketCheckConfig{URL: wsURL, Subprotocol: "chat"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := greenlight.NewWebSocketChecker(&greenlight.CheckConfig{
+				Name:      tt.name,
+				Timeout:   time.Second,
+				WebSocket: &tt.cfg,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checker.Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer plain.Close()
+	checker, err := greenlight.NewWebSocketChecker(&greenlight.CheckConfig{
+		Name:      "plain http",
+		Timeout:   time.Second,
+		WebSocket: &greenlight.WebSocketCheckConfig{URL: "ws" + strings.TrimPrefix(plain.URL, "http")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checker.Run(context.Background()); err == nil {
+		t.Error("expected an error for a server without websocket")
+	}
+}
+
+func TestWebSocketCheckerClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		onClose   func(rw *bufio.ReadWriter)
+		expectErr string
+	}{
+		{
+			name: "message before close",
+			onClose: func(rw *bufio.ReadWriter) {
+				rw.Write([]byte{0x81, 4, 'l', 'a', 't', 'e'})
+				rw.Write([]byte{0x88, 2, 0x03, 0xe8})
+				rw.Flush()
+			},
+		},
+		{
+			name: "no status",
+			onClose: func(rw *bufio.ReadWriter) {
+				rw.Write([]byte{0x88, 0})
+				rw.Flush()
+			},
+		},
+		{
+			name: "internal error",
+			onClose: func(rw *bufio.ReadWriter) {
+				rw.Write([]byte{0x88, 6, 0x03, 0xf3, 'o', 'o', 'p', 's'})
+				rw.Flush()
+			},
+			expectErr: "closed by server: status 1011 oops",
+		},
+		{
+			name: "invalid close frame",
+			onClose: func(rw *bufio.ReadWriter) {
+				rw.Write([]byte{0x88, 1, 0x03})
+				rw.Flush()
+			},
+			expectErr: "invalid close frame",
+		},
+		{
+			name:      "connection dropped",
+			onClose:   func(rw *bufio.ReadWriter) {},
+			expectErr: "EOF",
+		},
+		{
+			name: "server does not close",
+			onClose: func(rw *bufio.ReadWriter) {
+				io.Copy(io.Discard, rw) // until the client gives up
+			},
+			expectErr: "i/o timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(websocketHandler(tt.onClose))
+			defer ts.Close()
+			checker, err := greenlight.NewWebSocketChecker(&greenlight.CheckConfig{
+				Name:      tt.name,
+				Timeout:   300 * time.Millisecond,
+				WebSocket: &greenlight.WebSocketCheckConfig{URL: "ws" + strings.TrimPrefix(ts.URL, "http")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			err = checker.Run(context.Background())
+			if tt.expectErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.expectErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectErr)) {
+				t.Errorf("expected error %q, got: %v", tt.expectErr, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("close handshake must end in the timeout, took %s", elapsed)
+			}
+		})
+	}
+}